@@ -4,7 +4,7 @@ package constants
 const LAMBDA = 128
 const MEASURE_ROUNDS = 100
 const Q = 256
-const M = 44
+const M = 45 // with 44, the M equations in M+N token variables fall below LAMBDA bits
 const N = 112
 const RANDOM_SYS_SEED_LEN = LAMBDA
 const SALT_LEN = LAMBDA
@@ -17,5 +17,5 @@ const UOV_SK_SEED_LEN = 2 * LAMBDA
 // MQDSS
 const MQDSS_PK_SEED_LEN = LAMBDA
 const MQDSS_SK_SEED_LEN = 2 * LAMBDA
-const MQDSS_ROUNDS = 156
+const MQDSS_ROUNDS = 156 // crypto.MQDSSRounds(Q, LAMBDA)
 const FLEN = M * (N * (N + 1) / 2)
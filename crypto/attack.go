@@ -0,0 +1,249 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"mqat/math"
+)
+
+// Key-recovery attacks on UOV, for toy parameters only. Both recover the oil
+// space from the public key and return it in the layout of UOVSecretKey.O,
+// i.e. the (N-M) x M matrix O such that the oil vectors are (O y, y).
+
+var ErrAttackFailed = errors.New("crypto: attack did not recover the oil space")
+
+// ReconciliationAttack finds oil vectors one at a time. A first one is found
+// by solving P(o) = 0 on a random subspace of dimension N-M+1, which always
+// meets the oil space; each vector found restricts the next ones to the
+// orthogonal of the previous ones under the polar forms of P. Candidates that
+// do not extend to a full oil space are backtracked. The first step is an
+// exhaustive search over N-M variables, which is what makes unbalanced
+// parameters expensive.
+func (uov *UOV) ReconciliationAttack(pk *UOVPublicKey, seed []byte) ([]uint8, error) {
+	P, err := uov.PublicMap(pk)
+	if err != nil {
+		return nil, err
+	}
+	a := &oilAttack{uov: uov, P: P, seed: seed}
+	basis := a.reconcile(nil)
+	if basis == nil {
+		return nil, ErrAttackFailed
+	}
+	return a.oilMatrix(basis)
+}
+
+// KipnisShamirAttack uses that the oil space is an invariant subspace of
+// Q_j^-1 Q_i for the polar matrices Q_i, Q_j of any two combinations of the
+// public equations when N = 2M. Eigenvectors of such matrices are tested for
+// membership in the oil space until M independent ones are found. When N > 2M
+// an eigenvector only lies in the oil space with probability about
+// q^(2M-N), so the attack fails within the given number of tries.
+func (uov *UOV) KipnisShamirAttack(pk *UOVPublicKey, tries int, seed []byte) ([]uint8, error) {
+	P, err := uov.PublicMap(pk)
+	if err != nil {
+		return nil, err
+	}
+	F := uov.Field
+	n := uov.N
+	a := &oilAttack{uov: uov, P: P, seed: seed}
+	found := make([][]uint8, 0)
+	for try := 0; try < tries && len(found) < uov.M; try++ {
+		Qi := a.randomPolarMatrix()
+		Qj := math.InverseOver(F, a.randomPolarMatrix())
+		if Qj == nil {
+			continue
+		}
+		M := math.MulMatOver(F, Qj, Qi)
+		for lambda := 0; lambda < F.Order() && len(found) < uov.M; lambda++ {
+			ML := math.NewDenseMatrix(n, n, append([]uint8{}, M.Data...))
+			for i := 0; i < n; i++ {
+				ML.Set(i, i, F.Sub(ML.At(i, i), uint8(lambda)))
+			}
+			K := math.KernelOver(F, ML)
+			if _, d := K.Dims(); d == 0 || d > 3 {
+				continue
+			}
+			for _, o := range a.isotropic(K) {
+				if len(found) < uov.M && a.isOil(o, found) && a.independent(math.NewVector(o), found) {
+					found = append(found, o)
+				}
+			}
+		}
+	}
+	if len(found) < uov.M {
+		return nil, ErrAttackFailed
+	}
+	return a.oilMatrix(found)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+type oilAttack struct {
+	uov  *UOV
+	P    *math.MQSystem
+	seed []byte
+	ctr  int
+}
+
+func (a *oilAttack) random(n int) []uint8 {
+	a.ctr++
+	return domAttack.sample(a.uov.Field, n, a.seed, u32(a.ctr))
+}
+
+func (a *oilAttack) randomPolarMatrix() *math.Dense {
+	F := a.uov.Field
+	n := a.uov.N
+	coeffs := a.random(a.uov.M)
+	Q := math.NewDenseMatrix(n, n, nil)
+	for k, c := range coeffs {
+		Q = math.AddMatOver(F, Q, math.ScaleMatOver(F, a.P.PolarMatrix(k), c))
+	}
+	return Q
+}
+
+// reconcile extends the oil vectors found so far to a basis of the oil
+// space, or returns nil if they cannot be extended.
+func (a *oilAttack) reconcile(found [][]uint8) [][]uint8 {
+	F := a.uov.Field
+	m, n := a.uov.M, a.uov.N
+	if len(found) == m {
+		return found
+	}
+
+	// L = {x : G(o, x) = 0 for every o found} contains the oil space
+	constraints := math.NewDenseMatrix(len(found)*m, n, nil)
+	for i, o := range found {
+		for k := 0; k < m; k++ {
+			row := math.MulMatOver(F, math.T(math.NewVector(o)), a.P.PolarMatrix(k))
+			for j := 0; j < n; j++ {
+				constraints.Set(i*m+k, j, row.At(0, j))
+			}
+		}
+	}
+	L := math.KernelOver(F, constraints)
+	_, dimL := L.Dims()
+	d := dimL - m + 1
+	if d < 1 {
+		return nil
+	}
+	// R must meet span(found) trivially for R + span(found) to contain an
+	// oil vector outside span(found)
+	var R *math.Dense
+	for tries := 0; R == nil && tries < 16; tries++ {
+		R = math.MulMatOver(F, L, math.NewDenseMatrix(dimL, d, a.random(dimL*d)))
+		if !a.independent(R, found) {
+			R = nil
+		}
+	}
+	if R == nil {
+		return nil
+	}
+
+	candidates := a.isotropic(R)
+	for _, o := range candidates {
+		if !a.isOil(o, found) || !a.independent(math.NewVector(o), found) {
+			continue
+		}
+		if basis := a.reconcile(append(append([][]uint8{}, found...), o)); basis != nil {
+			return basis
+		}
+	}
+	return nil
+}
+
+// isOil reports whether P vanishes on o and its polar form vanishes between
+// o and every vector found.
+func (a *oilAttack) isOil(o []uint8, found [][]uint8) bool {
+	for _, v := range a.P.Eval(o) {
+		if v != 0 {
+			return false
+		}
+	}
+	for _, f := range found {
+		for _, v := range a.P.Polar(o, f) {
+			if v != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// independent reports whether the columns of V and the vectors found are
+// linearly independent.
+func (a *oilAttack) independent(V math.Matrix, found [][]uint8) bool {
+	n, d := V.Dims()
+	A := math.NewDenseMatrix(len(found)+d, n, nil)
+	for i, v := range found {
+		for j := 0; j < n; j++ {
+			A.Set(i, j, v[j])
+		}
+	}
+	for i := 0; i < d; i++ {
+		for j := 0; j < n; j++ {
+			A.Set(len(found)+i, j, V.At(j, i))
+		}
+	}
+	rank, _ := math.RowReduceOver(a.uov.Field, A)
+	return rank == len(found)+d
+}
+
+// isotropic returns the vectors o = R z with P(o) = 0, up to scaling. The
+// search is exhaustive over the columns of R but one.
+func (a *oilAttack) isotropic(R *math.Dense) [][]uint8 {
+	F := a.uov.Field
+	_, d := R.Dims()
+	PR, err := a.P.Compose(nil, R)
+	if err != nil {
+		return nil
+	}
+	c, err := math.NewChallenge(PR, nil)
+	if err != nil {
+		return nil
+	}
+	// the last nonzero coordinate of z is set to 1 and the following ones to 0
+	res := make([][]uint8, 0)
+	for p := d - 1; p >= 0; p-- {
+		tail := make([]uint8, d-p)
+		tail[0] = 1
+		zs := [][]uint8{{}}
+		if p > 0 {
+			fixed, err := c.Fix(tail)
+			if err != nil {
+				return nil
+			}
+			zs = math.ExhaustiveSearch(fixed, 0)
+		}
+		for _, z := range zs {
+			o := math.MulMatOver(F, R, math.NewVector(append(bytes.Clone(z), tail...))).Data
+			if p > 0 || a.isOil(o, nil) {
+				res = append(res, o)
+			}
+		}
+	}
+	return res
+}
+
+// oilMatrix returns the matrix O of the oil space spanned by basis.
+func (a *oilAttack) oilMatrix(basis [][]uint8) ([]uint8, error) {
+	F := a.uov.Field
+	m, n := a.uov.M, a.uov.N
+	B := math.NewDenseMatrix(n, m, nil)
+	bottom := math.NewDenseMatrix(m, m, nil)
+	for j, o := range basis {
+		for i := 0; i < n; i++ {
+			B.Set(i, j, o[i])
+			if i >= n-m {
+				bottom.Set(i-n+m, j, o[i])
+			}
+		}
+	}
+	inv := math.InverseOver(F, bottom)
+	if inv == nil {
+		return nil, ErrAttackFailed
+	}
+	Bn := math.MulMatOver(F, B, inv)
+	return Bn.Data[:(n-m)*m], nil
+}
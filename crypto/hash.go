@@ -0,0 +1,175 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"io"
+	"mqat/math"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Domain-separated hashing. Every hash and XOF call of the schemes goes
+// through a domain: the hashed string is the label of the domain followed by
+// the inputs, each prefixed by its length on 8 bytes. The encoding is
+// injective and the labels are distinct, so two calls only ever hash the
+// same string if they are the same call on the same inputs.
+
+type domain string
+
+const (
+	// UOV
+	domUOVOil     domain = "MQAT/UOV/oil"
+	domUOVPublic  domain = "MQAT/UOV/public"
+	domUOVVinegar domain = "MQAT/UOV/vinegar"
+	domUOVMessage domain = "MQAT/UOV/message"
+
+	// MAYO
+	domMAYOOil     domain = "MQAT/MAYO/oil"
+	domMAYOPublic  domain = "MQAT/MAYO/public"
+	domMAYOVinegar domain = "MQAT/MAYO/vinegar"
+
+	// MQDSS
+	domMQDSSP          domain = "MQDSS/P"
+	domMQDSSR          domain = "MQDSS/R"
+	domMQDSSSecret     domain = "MQDSS/secret"
+	domMQDSSC          domain = "MQDSS/C"
+	domMQDSSD          domain = "MQDSS/D"
+	domMQDSSSeed       domain = "MQDSS/seed"
+	domMQDSSRandomness domain = "MQDSS/randomness"
+	domMQDSSCom0       domain = "MQDSS/com0"
+	domMQDSSCom1       domain = "MQDSS/com1"
+	domMQDSSSigma0     domain = "MQDSS/sigma0"
+	domMQDSSAlpha      domain = "MQDSS/alpha"
+	domMQDSSH1         domain = "MQDSS/h1"
+	domMQDSSBits       domain = "MQDSS/bits"
+	domMQDSSRoot       domain = "MQDSS/root"
+	domMQDSSLeaf       domain = "MQDSS/leaf"
+	domSeedTree        domain = "MQDSS/seed tree"
+	domMerkleNode      domain = "MQDSS/Merkle node"
+
+	// interactive identification
+	domIDCommitment domain = "ID/commitment"
+	domIDAlpha      domain = "ID/alpha"
+
+	// MQOM
+	domMQOMSystem    domain = "MQOM/system"
+	domMQOMSecret    domain = "MQOM/secret"
+	domMQOMStatement domain = "MQOM/statement"
+	domMQOMSeed      domain = "MQOM/seed"
+	domMQOMDigest    domain = "MQOM/digest"
+	domMQOMRoots     domain = "MQOM/roots"
+	domMQOMCommit    domain = "MQOM/commit"
+	domMQOMShare     domain = "MQOM/share"
+	domMQOMH1        domain = "MQOM/h1"
+	domMQOMGamma     domain = "MQOM/gamma"
+	domMQOMH2        domain = "MQOM/h2"
+	domMQOMEps       domain = "MQOM/eps"
+	domMQOMH3        domain = "MQOM/h3"
+	domMQOMHidden    domain = "MQOM/hidden"
+
+	// MQAT
+	domMQATTarget       domain = "MQAT/target"
+	domMQATZStar        domain = "MQAT/z*"
+	domMQATRandomSystem domain = "MQAT/random system"
+	domMQATKeyID        domain = "MQAT/key ID"
+
+	// attacks on toy keys
+	domAttack domain = "MQAT/attack"
+)
+
+// hash is SHA3-256 of the encoding of the inputs.
+func (d domain) hash(inputs ...[]byte) []byte {
+	h := sha3.Sum256(d.encode(inputs...))
+	return h[:]
+}
+
+// xof is SHAKE256 absorbing the encoding of the inputs.
+func (d domain) xof(inputs ...[]byte) sha3.ShakeHash {
+	xof := sha3.NewShake256()
+	xof.Write(d.encode(inputs...))
+	return xof
+}
+
+func (d domain) bytes(n int, inputs ...[]byte) []byte {
+	out := make([]byte, n)
+	d.xof(inputs...).Read(out)
+	return out
+}
+
+func (d domain) sample(F math.Field, n int, inputs ...[]byte) []uint8 {
+	if n <= 0 {
+		return nil
+	}
+	return F.Sample(d.xof(inputs...), n)
+}
+
+// stream keys prg with the inputs, or with their hash if prg takes seeds of
+// a fixed length.
+func (d domain) stream(prg PRG, inputs ...[]byte) io.Reader {
+	if l := prg.SeedLen(); l > 0 {
+		return prg.Expand(d.hash(inputs...)[:l])
+	}
+	return prg.Expand(d.encode(inputs...))
+}
+
+// expand samples public data with prg.
+func (d domain) expand(prg PRG, F math.Field, n int, inputs ...[]byte) []uint8 {
+	if n <= 0 {
+		return nil
+	}
+	return F.Sample(d.stream(prg, inputs...), n)
+}
+
+func (d domain) system(prg PRG, F math.Field, m, n int, inputs ...[]byte) (*math.MQSystem, error) {
+	return math.NewMQSystemFromStream(F, m, n, d.stream(prg, inputs...))
+}
+
+// nonZero samples n nonzero elements of F, rejecting zeros.
+func (d domain) nonZero(F math.Field, n int, inputs ...[]byte) []uint8 {
+	xof := d.xof(inputs...)
+	out := make([]uint8, 0, n)
+	for len(out) < n {
+		for _, a := range F.Sample(xof, n-len(out)) {
+			if a != 0 {
+				out = append(out, a)
+			}
+		}
+	}
+	return out
+}
+
+// nonZeroExt samples n nonzero elements of the extension E, rejecting zeros.
+func (d domain) nonZeroExt(E *math.Extension, n int, inputs ...[]byte) []uint8 {
+	xof := d.xof(inputs...)
+	out := make([]uint8, 0, n*E.K)
+	for len(out) < n*E.K {
+		if a := E.Sample(xof, 1); !E.IsZero(a) {
+			out = append(out, a...)
+		}
+	}
+	return out
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+func (d domain) encode(inputs ...[]byte) []byte {
+	size := 8 + len(d)
+	for _, in := range inputs {
+		size += 8 + len(in)
+	}
+	out := make([]byte, 0, size)
+	out = binary.BigEndian.AppendUint64(out, uint64(len(d)))
+	out = append(out, d...)
+	for _, in := range inputs {
+		out = binary.BigEndian.AppendUint64(out, uint64(len(in)))
+		out = append(out, in...)
+	}
+	return out
+}
+
+// u32 encodes an index or a counter as a hash input.
+func u32(i int) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(i))
+}
@@ -0,0 +1,23 @@
+package crypto
+
+import (
+	"mqat/math"
+	"testing"
+)
+
+func TestMQDSSChallengesNonZero(t *testing.T) {
+	for name, F := range map[string]math.Field{"GF16": math.GF16, "GF31": math.GF31, "GF256": math.GF256} {
+		alphas := domMQDSSAlpha.nonZero(F, 10000, []byte{1})
+		seen := make(map[uint8]bool)
+		for _, a := range alphas {
+			if a == 0 {
+				t.Errorf("%s: sampled alpha = 0", name)
+				break
+			}
+			seen[a] = true
+		}
+		if len(seen) != F.Order()-1 {
+			t.Errorf("%s: sampled %d distinct challenges out of %d", name, len(seen), F.Order()-1)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	constants "mqat/const"
+)
+
+var (
+	ErrProtocolState    = errors.New("crypto: protocol message out of order")
+	ErrMalformedMessage = errors.New("crypto: malformed protocol message")
+)
+
+// Interactive 5-pass identification scheme of Sakumoto, Shirai and
+// Hiwatari, the R rounds of an MQDSS built with Interactive() run in
+// parallel:
+//
+//	P -> V  commitment: the hash of c0, c1 of every round
+//	V -> P  alphas: R nonzero challenges, packed
+//	P -> V  response: t1 and e1 of every round, packed as sigma1
+//	V -> P  bits: R challenge bits, packed
+//	P -> V  opening: r0 and c1, or r1 and c0, of every round
+//
+// Without Fiat-Shamir the cheater cannot grind the challenges offline, so
+// InteractiveRounds rounds are enough. Each prover and verifier runs the
+// protocol once and fails with ErrProtocolState when driven out of order.
+// A malformed message aborts the run of the prover.
+
+// IDProver is the prover state of one run of the protocol.
+type IDProver struct {
+	mqdss  *MQDSS
+	sk     *MQDSSSecretKey
+	step   int
+	rounds []*mqdssRound
+}
+
+// IDVerifier is the verifier state of one run of the protocol.
+type IDVerifier struct {
+	mqdss      *MQDSS
+	pk         *MQDSSPublicKey
+	step       int
+	commitment []byte
+	alphas     []uint8
+	t1s, e1s   []uint8
+	bits       []byte
+}
+
+func (mqdss *MQDSS) NewProver(sk *MQDSSSecretKey) *IDProver {
+	if !mqdss.Interactive || sk == nil {
+		return nil
+	}
+	return &IDProver{mqdss: mqdss, sk: sk}
+}
+
+func (mqdss *MQDSS) NewVerifier(pk *MQDSSPublicKey) *IDVerifier {
+	if !mqdss.Interactive || pk == nil {
+		return nil
+	}
+	return &IDVerifier{mqdss: mqdss, pk: pk}
+}
+
+// Commit samples the randomness of every round and returns the hash of the
+// commitments.
+func (p *IDProver) Commit() ([]byte, error) {
+	if p.step != 0 {
+		return nil, ErrProtocolState
+	}
+	mqdss := p.mqdss
+	seed := make([]byte, mqdss.SkSeedLen/8)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	p.rounds = mqdss.prepare(p.sk.Pk, seed, nil)
+	c := make([]byte, 0, 2*mqdss.R*constants.HASH_BYTES)
+	for _, r := range p.rounds {
+		mqdss.commit(p.sk, r)
+		c = append(c, r.c0...)
+		c = append(c, r.c1...)
+	}
+	p.step++
+	return domIDCommitment.hash(c), nil
+}
+
+// ChallengeAlphas receives the commitment and draws the first challenges.
+func (v *IDVerifier) ChallengeAlphas(commitment []byte) ([]byte, error) {
+	if v.step != 0 {
+		return nil, ErrProtocolState
+	}
+	if len(commitment) != constants.HASH_BYTES {
+		return nil, ErrMalformedMessage
+	}
+	mqdss := v.mqdss
+	seed := make([]byte, constants.HASH_BYTES)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	v.commitment = bytes.Clone(commitment)
+	if mqdss.Ext == nil {
+		v.alphas = domIDAlpha.nonZero(mqdss.Field, mqdss.R, seed)
+	} else {
+		v.alphas = domIDAlpha.nonZeroExt(mqdss.Ext, mqdss.R, seed)
+	}
+	v.step++
+	return mqdss.Field.Pack(v.alphas), nil
+}
+
+// Respond returns t1 = alpha r0 - t0 and e1 = alpha F(r0) - e0 for every
+// round.
+func (p *IDProver) Respond(alphas []byte) ([]byte, error) {
+	if p.step != 1 {
+		return nil, ErrProtocolState
+	}
+	mqdss := p.mqdss
+	k := mqdss.degree()
+	a := mqdss.Field.Unpack(alphas, mqdss.R*k)
+	if a == nil || len(alphas) != mqdss.Field.PackedLen(mqdss.R*k) {
+		return p.abort()
+	}
+	for i := 0; i < mqdss.R; i++ {
+		zero := true
+		for _, c := range a[i*k : (i+1)*k] {
+			zero = zero && c == 0
+		}
+		if zero {
+			return p.abort()
+		}
+	}
+	p.step++
+	return mqdss.respond(p.sk.Pk, p.rounds, a), nil
+}
+
+// ChallengeBits receives the responses and draws the challenge bits.
+func (v *IDVerifier) ChallengeBits(response []byte) ([]byte, error) {
+	if v.step != 1 {
+		return nil, ErrProtocolState
+	}
+	mqdss := v.mqdss
+	F := mqdss.Field
+	k := mqdss.degree()
+	lenT1 := F.PackedLen(mqdss.R * mqdss.N * k)
+	lenE1 := F.PackedLen(mqdss.R * mqdss.M * k)
+	if len(response) != lenT1+lenE1 {
+		return nil, ErrMalformedMessage
+	}
+	v.t1s = F.Unpack(response[:lenT1], mqdss.R*mqdss.N*k)
+	v.e1s = F.Unpack(response[lenT1:], mqdss.R*mqdss.M*k)
+	if v.t1s == nil || v.e1s == nil {
+		return nil, ErrMalformedMessage
+	}
+	v.bits = make([]byte, (mqdss.R+7)/8)
+	if _, err := rand.Read(v.bits); err != nil {
+		return nil, err
+	}
+	v.step++
+	return bytes.Clone(v.bits), nil
+}
+
+// Open reveals, for every round, r0 and c1 if its bit is 0 and r1 and c0
+// otherwise.
+func (p *IDProver) Open(bits []byte) ([]byte, error) {
+	if p.step != 2 {
+		return nil, ErrProtocolState
+	}
+	mqdss := p.mqdss
+	if len(bits) != (mqdss.R+7)/8 {
+		return p.abort()
+	}
+	opening := make([]byte, 0, mqdss.R*(mqdss.Field.PackedLen(mqdss.N)+constants.HASH_BYTES))
+	for i, r := range p.rounds {
+		if bit(bits, i) == 0 {
+			opening = append(opening, mqdss.Field.Pack(r.r0)...)
+			opening = append(opening, r.c1...)
+		} else {
+			opening = append(opening, mqdss.Field.Pack(r.r1)...)
+			opening = append(opening, r.c0...)
+		}
+	}
+	p.step++
+	wipeRounds(p.rounds)
+	p.rounds = nil
+	return opening, nil
+}
+
+// abort wipes the rounds and ends the run on a malformed message.
+func (p *IDProver) abort() ([]byte, error) {
+	p.step = -1
+	wipeRounds(p.rounds)
+	p.rounds = nil
+	return nil, ErrMalformedMessage
+}
+
+// Verify checks the opening against the commitment and the responses.
+func (v *IDVerifier) Verify(opening []byte) bool {
+	if v.step != 2 {
+		return false
+	}
+	v.step++
+	mqdss := v.mqdss
+	F := mqdss.Field
+	N, M, k := mqdss.N, mqdss.M, mqdss.degree()
+	lenR := F.PackedLen(N)
+	if len(opening) != mqdss.R*(lenR+constants.HASH_BYTES) {
+		return false
+	}
+	c := make([]byte, 0, 2*mqdss.R*constants.HASH_BYTES)
+	for i := 0; i < mqdss.R; i++ {
+		round := opening[i*(lenR+constants.HASH_BYTES) : (i+1)*(lenR+constants.HASH_BYTES)]
+		r_ch := F.Unpack(round[:lenR], N)
+		if r_ch == nil {
+			return false
+		}
+		c_ch := round[lenR:]
+		t1 := v.t1s[i*N*k : (i+1)*N*k]
+		e1 := v.e1s[i*M*k : (i+1)*M*k]
+		alpha := v.alphas[i*k : (i+1)*k]
+		if bit(v.bits, i) == 0 {
+			c = append(c, mqdss.check0(v.pk, alpha, r_ch, t1, e1)...)
+			c = append(c, c_ch...)
+		} else {
+			c = append(c, c_ch...)
+			c = append(c, mqdss.check1(v.pk, alpha, r_ch, t1, e1)...)
+		}
+	}
+	return bytes.Equal(v.commitment, domIDCommitment.hash(c))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+func bit(bits []byte, i int) byte {
+	return bits[i/8] >> (i % 8) & 1
+}
@@ -0,0 +1,273 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Encrypted issuer key files. A file is a header in the clear followed by
+// the key pair sealed with ChaCha20-Poly1305 under a key derived from the
+// passphrase with Argon2id:
+//
+//	"MQATKEY" | version (1) | name length (1) | parameter set name |
+//	key ID (16) | creation time (8, Unix seconds) |
+//	Argon2id time (4) | memory in KiB (4) | threads (1) | salt (16) |
+//	nonce (12) | ciphertext
+//
+// integers big endian. The header is the associated data of the AEAD, so
+// it cannot be altered without the passphrase, but ReadKeyFileHeader reads
+// it before any authentication. The plaintext is the fields of the secret
+// and public keys, each prefixed by its length on 4 bytes.
+
+var (
+	ErrKeyFile       = errors.New("crypto: malformed key file")
+	ErrPassphrase    = errors.New("crypto: wrong passphrase or corrupted key file")
+	ErrKeyFileParams = errors.New("crypto: key file is for another parameter set")
+)
+
+type KeyFileHeader struct {
+	// ParameterSet is the name of the parameter set of the key, empty for an
+	// MQAT not built from a ParameterSet.
+	ParameterSet string
+	// KeyID identifies the key pair, see MQAT.KeyID.
+	KeyID   []byte
+	Created time.Time
+}
+
+const (
+	keyFileMagic   = "MQATKEY"
+	keyFileVersion = 1
+	keyIDLen       = 16
+	keyFileSaltLen = 16
+)
+
+// The Argon2id cost of new files is the second recommendation of RFC 9106;
+// files with a cost above the bounds, 8 passes and 256 MiB, are rejected
+// before deriving the key, so that a crafted header cannot make loading it
+// take minutes or exhaust memory.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+
+	maxArgonTime   = 8
+	maxArgonMemory = 256 * 1024
+)
+
+// KeyID is the first 16 bytes of the hash of the public key.
+func (mqat *MQAT) KeyID(pk *MQATPublicKey) []byte {
+	return domMQATKeyID.hash(mqat.publicFields(pk)...)[:keyIDLen]
+}
+
+// EncryptSecretKey returns the key file of the issuer key pair, encrypted
+// under passphrase.
+func (mqat *MQAT) EncryptSecretKey(sk *MQATSecretKey, pk *MQATPublicKey, passphrase []byte) ([]byte, error) {
+	if err := mqat.ValidateKeys(sk, pk); err != nil {
+		return nil, err
+	}
+	header := &KeyFileHeader{ParameterSet: mqat.name, KeyID: mqat.KeyID(pk), Created: time.Now()}
+	var plaintext []byte
+	for _, f := range append(mqat.secretFields(sk), mqat.publicFields(pk)...) {
+		plaintext = binary.BigEndian.AppendUint32(plaintext, uint32(len(f)))
+		plaintext = append(plaintext, f...)
+	}
+	defer Wipe(plaintext)
+	return sealKeyFile(header, plaintext, passphrase)
+}
+
+// DecryptSecretKey opens a key file of EncryptSecretKey or
+// ReencryptKeyFile, which must be for the parameter set of mqat, and
+// checks that the keys match.
+func (mqat *MQAT) DecryptSecretKey(file, passphrase []byte) (*MQATSecretKey, *MQATPublicKey, error) {
+	if header, err := ReadKeyFileHeader(file); err != nil {
+		return nil, nil, err
+	} else if header.ParameterSet != mqat.name {
+		return nil, nil, ErrKeyFileParams
+	}
+	header, plaintext, err := openKeyFile(file, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer Wipe(plaintext)
+	var fields [][]byte
+	for rest := plaintext; len(rest) > 0; {
+		if len(rest) < 4 || uint64(len(rest)-4) < uint64(binary.BigEndian.Uint32(rest)) {
+			return nil, nil, ErrKeyFile
+		}
+		l := int(binary.BigEndian.Uint32(rest))
+		fields = append(fields, bytes.Clone(rest[4:4+l]))
+		rest = rest[4+l:]
+	}
+	sk, pk := mqat.keysFromFields(fields)
+	if sk == nil {
+		return nil, nil, ErrKeyFileParams
+	}
+	if err := mqat.ValidateKeys(sk, pk); err != nil {
+		sk.Destroy()
+		return nil, nil, err
+	}
+	if !bytes.Equal(mqat.KeyID(pk), header.KeyID) {
+		sk.Destroy()
+		return nil, nil, ErrKeyMismatch
+	}
+	return sk, pk, nil
+}
+
+// ReadKeyFileHeader returns the header of a key file without the
+// passphrase, e.g. to pick the parameter set; it is not authenticated until
+// the file is decrypted.
+func ReadKeyFileHeader(file []byte) (*KeyFileHeader, error) {
+	header, _, err := parseKeyFile(file)
+	return header, err
+}
+
+// ReencryptKeyFile encrypts the keys of file under new_passphrase, with a
+// fresh salt and the current Argon2id cost; the header is kept.
+func ReencryptKeyFile(file, old_passphrase, new_passphrase []byte) ([]byte, error) {
+	header, plaintext, err := openKeyFile(file, old_passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer Wipe(plaintext)
+	return sealKeyFile(header, plaintext, new_passphrase)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// keyFileKDF is the Argon2id cost and salt of a key file.
+type keyFileKDF struct {
+	time, memory uint32
+	threads      uint8
+	salt         []byte
+}
+
+func (kdf *keyFileKDF) key(passphrase []byte) []byte {
+	return argon2.IDKey(passphrase, kdf.salt, kdf.time, kdf.memory, kdf.threads, chacha20poly1305.KeySize)
+}
+
+func sealKeyFile(header *KeyFileHeader, plaintext, passphrase []byte) ([]byte, error) {
+	if len(header.ParameterSet) > 255 || len(header.KeyID) != keyIDLen {
+		return nil, ErrKeyFile
+	}
+	kdf := &keyFileKDF{time: argonTime, memory: argonMemory, threads: argonThreads, salt: make([]byte, keyFileSaltLen)}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	if _, err := rand.Read(kdf.salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte(keyFileMagic), keyFileVersion, uint8(len(header.ParameterSet)))
+	out = append(append(out, header.ParameterSet...), header.KeyID...)
+	out = binary.BigEndian.AppendUint64(out, uint64(header.Created.Unix()))
+	out = binary.BigEndian.AppendUint32(out, kdf.time)
+	out = binary.BigEndian.AppendUint32(out, kdf.memory)
+	out = append(append(append(out, kdf.threads), kdf.salt...), nonce...)
+
+	key := kdf.key(passphrase)
+	defer Wipe(key)
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, plaintext, out), nil
+}
+
+func openKeyFile(file, passphrase []byte) (*KeyFileHeader, []byte, error) {
+	header, kdf, err := parseKeyFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := kdf.key(passphrase)
+	defer Wipe(key)
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	n := headerLen(header)
+	plaintext, err := aead.Open(nil, file[n-chacha20poly1305.NonceSize:n], file[n:], file[:n])
+	if err != nil {
+		return nil, nil, ErrPassphrase
+	}
+	return header, plaintext, nil
+}
+
+func parseKeyFile(file []byte) (*KeyFileHeader, *keyFileKDF, error) {
+	m := len(keyFileMagic)
+	if len(file) < m+2 || string(file[:m]) != keyFileMagic || file[m] != keyFileVersion {
+		return nil, nil, ErrKeyFile
+	}
+	header := &KeyFileHeader{ParameterSet: string(file[m+2 : min(m+2+int(file[m+1]), len(file))])}
+	n := headerLen(header)
+	if len(file) < n+chacha20poly1305.Overhead {
+		return nil, nil, ErrKeyFile
+	}
+	rest := file[m+2+len(header.ParameterSet):]
+	header.KeyID = bytes.Clone(rest[:keyIDLen])
+	header.Created = time.Unix(int64(binary.BigEndian.Uint64(rest[keyIDLen:])), 0)
+	rest = rest[keyIDLen+8:]
+	kdf := &keyFileKDF{
+		time:    binary.BigEndian.Uint32(rest[0:4]),
+		memory:  binary.BigEndian.Uint32(rest[4:8]),
+		threads: rest[8],
+		salt:    rest[9 : 9+keyFileSaltLen],
+	}
+	if kdf.time == 0 || kdf.time > maxArgonTime || kdf.memory < 8*uint32(kdf.threads) ||
+		kdf.memory > maxArgonMemory || kdf.threads == 0 {
+		return nil, nil, ErrKeyFile
+	}
+	return header, kdf, nil
+}
+
+func headerLen(header *KeyFileHeader) int {
+	return len(keyFileMagic) + 2 + len(header.ParameterSet) + keyIDLen + 8 + 9 + keyFileSaltLen + chacha20poly1305.NonceSize
+}
+
+// secretFields and publicFields list the fields of the keys stored in a key
+// file, keysFromFields being the inverse of both.
+func (mqat *MQAT) secretFields(sk *MQATSecretKey) [][]byte {
+	if mqat.nist != nil {
+		return [][]byte{sk.nist_sk}
+	}
+	if mqat.mayo != nil {
+		return [][]byte{sk.mayo_sk.Seed, sk.mayo_sk.PkSeed, sk.mayo_sk.O, sk.mayo_sk.Si, sk.mayo_sk.P1i}
+	}
+	return [][]byte{sk.uov_sk.Seed, sk.uov_sk.PkSeed, sk.uov_sk.O, sk.uov_sk.Si, sk.uov_sk.P1i}
+}
+
+func (mqat *MQAT) publicFields(pk *MQATPublicKey) [][]byte {
+	if mqat.nist != nil {
+		return [][]byte{pk.nist_pk, pk.seed_random_sys}
+	}
+	if mqat.mayo != nil {
+		return [][]byte{pk.mayo_pk.Seed, pk.mayo_pk.P3i, pk.seed_random_sys}
+	}
+	return [][]byte{pk.uov_pk.Seed, pk.uov_pk.P1i, pk.uov_pk.P2i, pk.uov_pk.P3i, pk.seed_random_sys}
+}
+
+// keysFromFields returns nil if the number of fields is not that of the
+// keys of mqat.
+func (mqat *MQAT) keysFromFields(f [][]byte) (*MQATSecretKey, *MQATPublicKey) {
+	switch {
+	case mqat.nist != nil && len(f) == 3:
+		return &MQATSecretKey{nist_sk: f[0]}, &MQATPublicKey{nist_pk: f[1], seed_random_sys: f[2]}
+	case mqat.mayo != nil && len(f) == 8:
+		sk := &MAYOSecretKey{Seed: f[0], PkSeed: f[1], O: f[2], Si: f[3], P1i: f[4]}
+		pk := &MAYOPublicKey{Seed: f[5], P3i: f[6]}
+		return &MQATSecretKey{mayo_sk: sk}, &MQATPublicKey{mayo_pk: pk, seed_random_sys: f[7]}
+	case mqat.uov != nil && len(f) == 10:
+		sk := &UOVSecretKey{Seed: f[0], PkSeed: f[1], O: f[2], Si: f[3], P1i: f[4]}
+		pk := &UOVPublicKey{Seed: f[5], P1i: f[6], P2i: f[7], P3i: f[8]}
+		return &MQATSecretKey{uov_sk: sk}, &MQATPublicKey{uov_pk: pk, seed_random_sys: f[9]}
+	}
+	return nil, nil
+}
@@ -0,0 +1,352 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"mqat/math"
+)
+
+// MAYO (Beullens, SAC 2021) shrinks the UOV public key by taking an oil
+// space of dimension o much smaller than m. The public map P of m equations
+// in n variables then has too few oil variables to be inverted, so it is
+// whipped into
+//
+//	P*(s_1, ..., s_k) = sum_i E^l(i,i) P(s_i) + sum_i<j E^l(i,j) P'(s_i, s_j)
+//
+// over k*n variables, P' being the polar form of P and E the multiplication
+// by z modulo f(z) = z^m + z^3 + z + 2 on F^m seen as polynomials of degree
+// < m. P* vanishes on the k copies of the oil space, of dimension k*o >= m,
+// and the owner of O inverts it by solving a linear system of m equations in
+// k*o variables.
+
+// WithMAYO makes the MQAT issuer sign with MAYO, an oil space of dimension
+// o whipped k times, instead of UOV. The responses grow from N to k*N field
+// elements and the issuer public key shrinks to a seed and P3.
+func WithMAYO(o, k int) Option {
+	return func(opt *options) { opt.mayoO, opt.mayoK, opt.set = o, k, opt.set|optMAYO }
+}
+
+func NewMAYO(m, n, o, k, pk_seed_len, sk_seed_len int, opts ...Option) *MAYO {
+	return NewMAYOOver(math.GF256, m, n, o, k, pk_seed_len, sk_seed_len, opts...)
+}
+
+func NewMAYOOver(F math.Field, m, n, o, k, pk_seed_len, sk_seed_len int, opts ...Option) *MAYO {
+	if m <= 3 || o <= 0 || n <= o || k <= 0 || k*o < m || k*(k+1)/2 > m ||
+		pk_seed_len <= 0 || sk_seed_len <= 0 {
+		return nil
+	}
+	opt := newOptions(opts)
+	if !opt.accepts("MAYO", optPRG) {
+		return nil
+	}
+	if !secure("MAYO", EstimateMAYO(F.Order(), m, n, o, k), opt) {
+		return nil
+	}
+	mayo := new(MAYO)
+	mayo.Field = F
+	mayo.M = m
+	mayo.N = n
+	mayo.O = o
+	mayo.K = k
+	mayo.PkSeedLen = pk_seed_len
+	mayo.SkSeedLen = sk_seed_len
+	mayo.PRG = opt.prg
+	return mayo
+}
+
+func (mayo *MAYO) KeyGen() (*MAYOSecretKey, *MAYOPublicKey) {
+	F := mayo.Field
+	m, n, o := mayo.M, mayo.N, mayo.O
+	seed_sk := make([]byte, mayo.SkSeedLen/8)
+	if _, err := rand.Read(seed_sk); err != nil {
+		return nil, nil
+	}
+	seed_pk := make([]byte, mayo.PkSeedLen/8)
+	if _, err := rand.Read(seed_pk); err != nil {
+		return nil, nil
+	}
+
+	O := domMAYOOil.sample(F, (n-o)*o, seed_sk)
+	Pi1, Pi2 := mayo.expandP(seed_pk)
+	if O == nil || Pi1 == nil {
+		return nil, nil
+	}
+	Pi3 := derivePi3(F, O, Pi1, Pi2, m, o, n)
+	if Pi3 == nil {
+		return nil, nil
+	}
+	sk := &MAYOSecretKey{
+		Seed:   seed_sk,
+		PkSeed: bytes.Clone(seed_pk),
+		O:      O,
+		Si:     deriveSi(F, O, Pi1, Pi2, m, o, n),
+		P1i:    Pi1,
+	}
+	return sk, &MAYOPublicKey{Seed: seed_pk, P3i: Pi3}
+}
+
+// Sign returns a preimage of target, M field elements, under the whipped
+// map: K vectors of N elements.
+func (mayo *MAYO) Sign(target []uint8, sk *MAYOSecretKey) []uint8 {
+	if sk == nil || len(target) != mayo.M {
+		return nil
+	}
+	F := mayo.Field
+	m, n, o, k := mayo.M, mayo.N, mayo.O, mayo.K
+	v := n - o
+	P1, err := math.NewMQSystemFromOil(F, m, v, 0, sk.P1i, nil, nil)
+	if err != nil || len(sk.Si) != m*v*o || len(sk.O) != v*o {
+		return nil
+	}
+	OM := math.NewDenseMatrix(v, o, sk.O)
+	S := make([]*math.Dense, m)
+	for r := range S {
+		S[r] = math.NewDenseMatrix(v, o, sk.Si[r*v*o:(r+1)*v*o])
+	}
+
+	for ctr := 0; ctr < 256; ctr++ {
+		rnd := domMAYOVinegar.sample(F, k*v+k*o, target, sk.Seed, []byte{byte(ctr)})
+		// P(v_i + O x_i, x_i) = P1(v_i) + L_i x_i, the rows of L_i being
+		// v_i^T S_r
+		vs := make([][]uint8, k)
+		L := make([]*math.Dense, k)
+		for i := range vs {
+			vs[i] = rnd[i*v : (i+1)*v]
+			L[i] = math.NewDenseMatrix(m, o, nil)
+			vt := math.T(math.NewVector(vs[i]))
+			for r := 0; r < m; r++ {
+				row := math.MulMatOver(F, vt, S[r])
+				for c := 0; c < o; c++ {
+					L[i].Set(r, c, row.At(0, c))
+				}
+			}
+		}
+
+		// P'(s_i, s_j) = P1'(v_i, v_j) + L_i x_j + L_j x_i, so P* is the
+		// whipped map of the vinegar plus A x
+		terms := make([][]uint8, k*(k+1)/2)
+		for i := 0; i < k; i++ {
+			terms[pairIndex(k, i, i)] = P1.Eval(vs[i])
+			for j := i + 1; j < k; j++ {
+				terms[pairIndex(k, i, j)] = P1.Polar(vs[i], vs[j])
+			}
+		}
+		y := mayo.whip(terms)
+		Wipe(terms...)
+		A := math.NewDenseMatrix(m, k*o+1, nil)
+		wipe := func() {
+			Wipe(rnd, y, A.Data)
+			for _, l := range L {
+				Wipe(l.Data)
+			}
+		}
+		for r := 0; r < m; r++ {
+			A.Set(r, k*o, F.Sub(target[r], y[r]))
+		}
+		for j := 0; j < k; j++ {
+			for c := 0; c < o; c++ {
+				terms := make([][]uint8, k*(k+1)/2)
+				for i := 0; i < k; i++ {
+					col := make([]uint8, m)
+					for r := range col {
+						col[r] = L[i].At(r, c)
+					}
+					terms[pairIndex(k, min(i, j), max(i, j))] = col
+				}
+				for r, a := range mayo.whip(terms) {
+					A.Set(r, j*o+c, a)
+				}
+			}
+		}
+
+		rank, pivots := math.RowReduceOver(F, A)
+		if rank < m || pivots[m-1] == k*o {
+			wipe()
+			continue
+		}
+		// the free variables keep their random values
+		x := rnd[k*v:]
+		pivot := make([]bool, k*o)
+		for _, p := range pivots {
+			pivot[p] = true
+		}
+		for r, p := range pivots {
+			val := A.At(r, k*o)
+			for c := 0; c < k*o; c++ {
+				if !pivot[c] {
+					val = F.Sub(val, F.Mul(A.At(r, c), x[c]))
+				}
+			}
+			x[p] = val
+		}
+
+		sig := make([]uint8, 0, k*n)
+		for i := 0; i < k; i++ {
+			xi := x[i*o : (i+1)*o]
+			Ox := math.MulMatOver(F, OM, math.NewVector(xi))
+			for a := 0; a < v; a++ {
+				sig = append(sig, F.Add(vs[i][a], Ox.Data[a]))
+			}
+			sig = append(sig, xi...)
+			Wipe(Ox.Data)
+		}
+		wipe()
+		return sig
+	}
+	return nil
+}
+
+func (mayo *MAYO) Verify(target, signature []uint8, pk *MAYOPublicKey) bool {
+	m, n, k := mayo.M, mayo.N, mayo.K
+	P, err := mayo.PublicMap(pk)
+	if err != nil || len(target) != m || len(signature) != k*n {
+		return false
+	}
+	terms := make([][]uint8, k*(k+1)/2)
+	for i := 0; i < k; i++ {
+		si := signature[i*n : (i+1)*n]
+		terms[pairIndex(k, i, i)] = P.Eval(si)
+		for j := i + 1; j < k; j++ {
+			terms[pairIndex(k, i, j)] = P.Polar(si, signature[j*n:(j+1)*n])
+		}
+	}
+	return bytes.Equal(mayo.whip(terms), target)
+}
+
+// PublicMap returns P, the M equations in N variables the whipped map is
+// built from.
+func (mayo *MAYO) PublicMap(pk *MAYOPublicKey) (*math.MQSystem, error) {
+	if pk == nil || len(pk.Seed) != mayo.PkSeedLen/8 {
+		return nil, ErrUOVKey
+	}
+	Pi1, Pi2 := mayo.expandP(pk.Seed)
+	if Pi1 == nil {
+		return nil, ErrUOVKey
+	}
+	return math.NewMQSystemFromOil(mayo.Field, mayo.M, mayo.N, mayo.O, Pi1, Pi2, pk.P3i)
+}
+
+// WhippedMap returns P* as an MQSystem of M equations in K*N variables,
+// the variables of s_i coming i-th.
+func (mayo *MAYO) WhippedMap(pk *MAYOPublicKey) (*math.MQSystem, error) {
+	P, err := mayo.PublicMap(pk)
+	if err != nil {
+		return nil, err
+	}
+	F := mayo.Field
+	m, n, k := mayo.M, mayo.N, mayo.K
+	N := k * n
+	stride := N * (N + 1) / 2
+	coeffs := make([]uint8, m*stride)
+	add := func(a, b int, y []uint8) {
+		pos := N*a + b - a*(a+1)/2
+		for r, e := range y {
+			coeffs[r*stride+pos] = F.Add(coeffs[r*stride+pos], e)
+		}
+	}
+	// the monomial x_a x_b of P contributes E^l (s_i)_a (s_i)_b to P* for
+	// l = l(i,i), and E^l ((s_i)_a (s_j)_b + (s_i)_b (s_j)_a) for l = l(i,j)
+	for a := 0; a < n; a++ {
+		for b := a; b < n; b++ {
+			y := make([]uint8, m)
+			for r := range y {
+				y[r] = P.At(r, a, b)
+			}
+			for i := 0; i < k; i++ {
+				add(i*n+a, i*n+b, y)
+				y = mayo.mulZ(y)
+				for j := i + 1; j < k; j++ {
+					add(i*n+a, j*n+b, y)
+					add(i*n+b, j*n+a, y)
+					y = mayo.mulZ(y)
+				}
+			}
+		}
+	}
+	return math.NewMQSystem(F, m, N, coeffs)
+}
+
+// ValidateSecretKey checks the dimensions of sk and, if pk is not nil, that
+// the two keys form a pair.
+func (mayo *MAYO) ValidateSecretKey(sk *MAYOSecretKey, pk *MAYOPublicKey) error {
+	F := mayo.Field
+	m, n, o := mayo.M, mayo.N, mayo.O
+	v := n - o
+	if sk == nil || len(sk.Seed) == 0 || len(sk.O) != v*o || len(sk.Si) != m*v*o || len(sk.P1i) != m*v*(v+1)/2 {
+		return ErrUOVKey
+	}
+	if !inField(F, sk.O, sk.Si, sk.P1i) {
+		return ErrUOVKey
+	}
+	if pk == nil {
+		return nil
+	}
+	if len(pk.Seed) != mayo.PkSeedLen/8 || len(pk.P3i) != m*o*(o+1)/2 || !inField(F, pk.P3i) {
+		return ErrUOVKey
+	}
+	Pi1, Pi2 := mayo.expandP(pk.Seed)
+	if Pi1 == nil {
+		return ErrUOVKey
+	}
+	if !bytes.Equal(sk.P1i, Pi1) ||
+		!bytes.Equal(sk.Si, deriveSi(F, sk.O, Pi1, Pi2, m, o, n)) ||
+		!bytes.Equal(pk.P3i, derivePi3(F, sk.O, Pi1, Pi2, m, o, n)) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+// PublicKeySize is the length in bytes of the seed and the packed P3.
+func (mayo *MAYO) PublicKeySize() int {
+	return mayo.PkSeedLen/8 + mayo.Field.PackedLen(mayo.M*mayo.O*(mayo.O+1)/2)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// mayoTail holds the terms of f(z) - z^m as (degree, coefficient).
+var mayoTail = [][2]int{{0, 2}, {1, 1}, {3, 1}}
+
+// expandP expands P1 and P2, one block per equation, from the public seed.
+func (mayo *MAYO) expandP(seed []byte) ([]uint8, []uint8) {
+	m, o, v := mayo.M, mayo.O, mayo.N-mayo.O
+	lenP1 := m * v * (v + 1) / 2
+	Pi12 := domMAYOPublic.expand(mayo.PRG, mayo.Field, lenP1+m*v*o, seed)
+	if Pi12 == nil {
+		return nil, nil
+	}
+	return Pi12[:lenP1], Pi12[lenP1:]
+}
+
+// mulZ returns E y, the product of y by z modulo f.
+func (mayo *MAYO) mulZ(y []uint8) []uint8 {
+	F := mayo.Field
+	m := mayo.M
+	res := make([]uint8, m)
+	copy(res[1:], y[:m-1])
+	for _, t := range mayoTail {
+		res[t[0]] = F.Sub(res[t[0]], F.Mul(uint8(t[1]), y[m-1]))
+	}
+	return res
+}
+
+// whip returns the sum of E^l terms[l], by Horner's rule; nil terms are
+// zero.
+func (mayo *MAYO) whip(terms [][]uint8) []uint8 {
+	F := mayo.Field
+	acc := make([]uint8, mayo.M)
+	for l := len(terms) - 1; l >= 0; l-- {
+		acc = mayo.mulZ(acc)
+		for r, a := range terms[l] {
+			acc[r] = F.Add(acc[r], a)
+		}
+	}
+	return acc
+}
+
+// pairIndex is l(i, j), the rank of the pair i <= j < k in the order
+// (0,0), (0,1), ..., (0,k-1), (1,1), ...
+func pairIndex(k, i, j int) int {
+	return i*k - i*(i-1)/2 + j - i
+}
@@ -0,0 +1,108 @@
+package crypto
+
+import constants "mqat/const"
+
+// Merkle tree over commitments, with batch openings: given the leaves the
+// verifier can recompute, the opening is the set of highest nodes whose
+// subtrees contain none of them. Leaves are padded to a power of two with
+// zero leaves, which the verifier always knows.
+
+type merkleTree struct {
+	nodes  [][]byte
+	leaves int
+}
+
+func newMerkleTree(leaves [][]byte) *merkleTree {
+	d := treeDepth(len(leaves))
+	t := &merkleTree{nodes: make([][]byte, (2<<d)-1), leaves: len(leaves)}
+	for i := 0; i < 1<<d; i++ {
+		t.nodes[(1<<d)-1+i] = merkleLeaf(leaves, i)
+	}
+	for i := (1 << d) - 2; i >= 0; i-- {
+		t.nodes[i] = merkleNode(t.nodes[2*i+1], t.nodes[2*i+2])
+	}
+	return t
+}
+
+func (t *merkleTree) root() []byte {
+	return t.nodes[0]
+}
+
+// open returns the nodes the verifier needs to recompute the root from the
+// known leaves.
+func (t *merkleTree) open(known []bool) [][]byte {
+	res := make([][]byte, 0)
+	walkMerkle(known, func(i int) []byte {
+		res = append(res, t.nodes[i])
+		return t.nodes[i]
+	}, func(i int) []byte {
+		return t.nodes[i]
+	})
+	return res
+}
+
+// merkleRoot recomputes the root from the leaves, nil when unknown, and the
+// opening. It returns nil if the opening has the wrong number of nodes.
+func merkleRoot(leaves [][]byte, opening [][]byte) []byte {
+	known := make([]bool, len(leaves))
+	for i, l := range leaves {
+		known[i] = l != nil
+	}
+	d := treeDepth(len(leaves))
+	next := 0
+	root := walkMerkle(known, func(int) []byte {
+		if next >= len(opening) {
+			return nil
+		}
+		next++
+		return opening[next-1]
+	}, func(i int) []byte {
+		return merkleLeaf(leaves, i-(1<<d)+1)
+	})
+	if root == nil || next != len(opening) {
+		return nil
+	}
+	return root
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+func merkleNode(l, r []byte) []byte {
+	if l == nil || r == nil {
+		return nil
+	}
+	return domMerkleNode.hash(l, r)
+}
+
+func merkleLeaf(leaves [][]byte, i int) []byte {
+	if i >= len(leaves) {
+		return make([]byte, constants.HASH_BYTES)
+	}
+	return leaves[i]
+}
+
+// walkMerkle computes the root depth first, taking the highest nodes without
+// known leaves from opened and the known leaves from leaf.
+func walkMerkle(known []bool, opened, leaf func(i int) []byte) []byte {
+	d := treeDepth(len(known))
+	some := make([]bool, (2<<d)-1)
+	for i := 0; i < 1<<d; i++ {
+		some[(1<<d)-1+i] = i >= len(known) || known[i]
+	}
+	for i := (1 << d) - 2; i >= 0; i-- {
+		some[i] = some[2*i+1] || some[2*i+2]
+	}
+	var walk func(i int) []byte
+	walk = func(i int) []byte {
+		switch {
+		case !some[i]:
+			return opened(i)
+		case i >= (1<<d)-1:
+			return leaf(i)
+		}
+		return merkleNode(walk(2*i+1), walk(2*i+2))
+	}
+	return walk(0)
+}
@@ -1,22 +1,55 @@
 package crypto
 
+import (
+	"io"
+	"mqat/math"
+)
+
+// The schemes are not modified after construction and their methods never
+// write to their arguments, so an MQAT, UOV, NISTUOV, MAYO, MQDSS or MQOM
+// and its keys can be shared by any number of goroutines; NISTUOV.Rand must
+// then be safe for concurrent use too, as crypto/rand is. IDProver and
+// IDVerifier hold the state of one session and must not be shared, and
+// MQDSSPrecomputed is consumed under a lock by its first use.
+
 // //////////////////////////////////////
 // MQAT
 // //////////////////////////////////////
 type MQAT struct {
+	name                string // of the ParameterSet, if built from one
+	Field               math.Field
 	N, M                int
 	salt_len            int
 	random_sys_seed_len int
+	prg                 PRG
+	mode                SigningMode
 	uov                 *UOV
-	mqdss               *MQDSS
+	nist                *NISTUOV // issuer instead of uov if not nil
+	mayo                *MAYO    // issuer instead of uov if not nil
+	proof               proofSystem
+	id                  *MQDSS // interactive redemption
+}
+
+// proofSystem proves knowledge of a preimage under an MQ map, with the keys
+// of MQDSS: MQDSS itself or MQOM.
+type proofSystem interface {
+	KeyPair(F *math.MQSystem, S, V []uint8) (*MQDSSSecretKey, *MQDSSPublicKey)
+	SignWith(message []uint8, sk *MQDSSSecretKey, mode SigningMode) []byte
+	Verify(message []uint8, sig []byte, pk *MQDSSPublicKey) bool
+	SignatureSize() int
+	ExpectedSignatureSize() int
 }
 
 type MQATSecretKey struct {
-	uov_sk *UOVSecretKey
+	uov_sk  *UOVSecretKey
+	nist_sk []byte
+	mayo_sk *MAYOSecretKey
 }
 
 type MQATPublicKey struct {
 	uov_pk          *UOVPublicKey
+	nist_pk         []byte
+	mayo_pk         *MAYOPublicKey
 	seed_random_sys []byte
 }
 
@@ -29,16 +62,19 @@ type MQATToken struct {
 // UOV
 // //////////////////////////////////////
 type UOV struct {
+	Field     math.Field
 	M, N      int
 	PkSeedLen int
 	SkSeedLen int
+	PRG       PRG // expansion of the public matrices
 }
 
 type UOVSecretKey struct {
-	Seed []byte
-	O    []uint8
-	Si   []uint8
-	P1i  []uint8
+	Seed   []byte
+	PkSeed []byte // seed of P1 and P2
+	O      []uint8
+	Si     []uint8
+	P1i    []uint8
 }
 
 type UOVPublicKey struct {
@@ -48,24 +84,80 @@ type UOVPublicKey struct {
 	P3i  []uint8
 }
 
+// NISTUOV is UOV as specified for the NIST additional signatures, round 2,
+// with keys and signatures in the encodings of the specification.
+type NISTUOV struct {
+	UOVParams
+	Variant UOVVariant
+	Rand    io.Reader // secret seeds and salts, crypto/rand if nil
+}
+
 // //////////////////////////////////////
-// MQDSS
+// MAYO
 // //////////////////////////////////////
-type MQDSS struct {
+
+// MAYO is UOV with an oil space of dimension O < M, whose public map is
+// whipped K times into a map of K*N variables with an oil space of
+// dimension K*O >= M.
+type MAYO struct {
+	Field     math.Field
 	M, N      int
-	R         int
+	O, K      int
 	PkSeedLen int
 	SkSeedLen int
+	PRG       PRG // expansion of P1 and P2
+}
+
+type MAYOSecretKey struct {
+	Seed   []byte
+	PkSeed []byte
+	O      []uint8
+	Si     []uint8
+	P1i    []uint8
+}
+
+// MAYOPublicKey is compressed: P1 and P2 are expanded from Seed.
+type MAYOPublicKey struct {
+	Seed []byte
+	P3i  []uint8
+}
+
+// //////////////////////////////////////
+// MQDSS
+// //////////////////////////////////////
+type MQDSS struct {
+	Field   math.Field
+	Ext     *math.Extension // challenges and masks, nil for Field itself
+	Compact bool            // seed tree and Merkle tree signature format
+	// Interactive restricts mqdss to the interactive protocol, its rounds
+	// being too few for Fiat-Shamir signatures.
+	Interactive bool
+	M, N        int
+	R           int
+	PkSeedLen   int
+	SkSeedLen   int
+	PRG         PRG // expansion of the public system
+	Mode        SigningMode
 }
 
 type MQDSSPublicKey struct {
-	P1 []uint8
-	P2 []uint8
-	P3 []uint8
-	R  []uint8
-	V  []uint8
+	F *math.MQSystem
+	V []uint8
 }
 type MQDSSSecretKey struct {
 	S  []uint8
 	Pk *MQDSSPublicKey
 }
+
+// //////////////////////////////////////
+// MQOM
+// //////////////////////////////////////
+type MQOM struct {
+	Field math.Field
+	Ext   *math.Extension // challenges and masks
+	M, N  int
+	Tau   int // repetitions
+	D     int // log2 of the number of parties
+	PRG   PRG // expansion of the public system
+	Mode  SigningMode
+}
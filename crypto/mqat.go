@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto/rand"
 	constants "mqat/const"
 	"mqat/math"
@@ -17,19 +18,89 @@ func NewMQAT(
 	mqdss_rounds int,
 	mqdss_sk_seed_len int,
 	mqdss_pk_seed_len int,
+	opts ...Option,
 ) *MQAT {
-	if m <= 0 || n <= 0 || m > n || salt_len <= 0 ||
+	return NewMQATOver(
+		math.GF256,
+		n, m,
+		salt_len,
+		uov_pk_seed_len, uov_sk_seed_len,
+		random_sys_seed_len,
+		mqdss_rounds, mqdss_sk_seed_len, mqdss_pk_seed_len,
+		opts...,
+	)
+}
+
+func NewMQATOver(
+	F math.Field,
+	n, m int,
+	salt_len int,
+	uov_pk_seed_len int,
+	uov_sk_seed_len int,
+	random_sys_seed_len int,
+	mqdss_rounds int,
+	mqdss_sk_seed_len int,
+	mqdss_pk_seed_len int,
+	opts ...Option,
+) *MQAT {
+	o := newOptions(opts)
+	if !o.accepts("MQAT", optExtension|optCompact|optMQOM|optPRG|optSigningMode|optNISTUOV|optMAYO) {
+		return nil
+	}
+	// the issued responses are in P's variables: N, or K*N with MAYO
+	issued := n
+	if o.mayoK > 0 {
+		issued = o.mayoK * n
+	}
+	if m <= 0 || n <= 0 || m > issued || salt_len <= 0 ||
 		uov_pk_seed_len <= 0 || uov_sk_seed_len <= 0 ||
 		mqdss_rounds <= 0 {
 		return nil
 	}
+	if !secure("MQAT", estimateMQATWith(F.Order(), m, n, mqdss_rounds, o), o) {
+		return nil
+	}
 	mqat := new(MQAT)
+	mqat.Field = F
 	mqat.M = m
 	mqat.N = n
 	mqat.salt_len = salt_len
 	mqat.random_sys_seed_len = random_sys_seed_len
-	mqat.uov = NewUOV(m, n, uov_pk_seed_len, uov_sk_seed_len)
-	mqat.mqdss = NewMQDSS(m, m+n, mqdss_rounds, mqdss_pk_seed_len, mqdss_sk_seed_len)
+	mqat.prg = o.prg
+	mqat.mode = o.mode
+	// the estimate above covers both building blocks; exactly one of nist,
+	// mayo and uov is set
+	switch {
+	case o.nist != 0 && o.mayoK > 0:
+		logrus.Error("MQAT cannot issue with both NIST UOV and MAYO")
+		return nil
+	case o.nist != 0:
+		for _, p := range []UOVParams{UOV_Is, UOV_Ip, UOV_III, UOV_V} {
+			if p.Field == F && p.N == n && p.M == m {
+				mqat.nist = p.New(o.nist)
+			}
+		}
+	case o.mayoK > 0:
+		mqat.mayo = NewMAYOOver(F, m, n, o.mayoO, o.mayoK, uov_pk_seed_len, uov_sk_seed_len, WithPRG(o.prg), Insecure())
+	default:
+		mqat.uov = NewUOVOver(F, m, n, uov_pk_seed_len, uov_sk_seed_len, WithPRG(o.prg), Insecure())
+	}
+	popts := []Option{WithExtension(o.degree), WithPRG(o.prg), WithSigningMode(o.mode), Insecure()}
+	if o.compact {
+		popts = append(popts, Compact())
+	}
+	if o.mqom > 0 {
+		if mqom := NewMQOMOver(F, m, m+issued, mqdss_rounds, o.mqom, popts...); mqom != nil {
+			mqat.proof = mqom
+		}
+	} else if mqdss := NewMQDSSOver(F, m, m+issued, mqdss_rounds, mqdss_pk_seed_len, mqdss_sk_seed_len, popts...); mqdss != nil {
+		mqat.proof = mqdss
+	}
+	mqat.id = NewMQDSSOver(F, m, m+issued, InteractiveRounds(pow(F.Order(), o.degree)-1, o.target),
+		mqdss_pk_seed_len, mqdss_sk_seed_len, WithExtension(o.degree), WithPRG(o.prg), Interactive(), Insecure())
+	if (mqat.uov == nil && mqat.mayo == nil && mqat.nist == nil) || mqat.proof == nil || mqat.id == nil {
+		return nil
+	}
 	return mqat
 }
 
@@ -37,10 +108,30 @@ func (mqat *MQAT) KeyGen() (*MQATSecretKey, *MQATPublicKey) {
 	sk := new(MQATSecretKey)
 	pk := new(MQATPublicKey)
 
-	uov_sk, uov_pk := mqat.uov.KeyGen()
-	if uov_sk == nil || uov_pk == nil {
-		logrus.Error("Could not generate UOV public key")
-		return nil, nil
+	if mqat.nist != nil {
+		nist_sk, nist_pk, err := mqat.nist.KeyGen()
+		if err != nil {
+			logrus.Error("Could not generate UOV public key")
+			return nil, nil
+		}
+		sk.nist_sk = nist_sk
+		pk.nist_pk = nist_pk
+	} else if mqat.mayo != nil {
+		mayo_sk, mayo_pk := mqat.mayo.KeyGen()
+		if mayo_sk == nil || mayo_pk == nil {
+			logrus.Error("Could not generate MAYO public key")
+			return nil, nil
+		}
+		sk.mayo_sk = mayo_sk
+		pk.mayo_pk = mayo_pk
+	} else {
+		uov_sk, uov_pk := mqat.uov.KeyGen()
+		if uov_sk == nil || uov_pk == nil {
+			logrus.Error("Could not generate UOV public key")
+			return nil, nil
+		}
+		sk.uov_sk = uov_sk
+		pk.uov_pk = uov_pk
 	}
 
 	random_sys_seed := make([]byte, mqat.random_sys_seed_len/8)
@@ -50,22 +141,44 @@ func (mqat *MQAT) KeyGen() (*MQATSecretKey, *MQATPublicKey) {
 		return nil, nil
 	}
 
-	sk.uov_sk = uov_sk
 	pk.seed_random_sys = random_sys_seed
-	pk.uov_pk = uov_pk
 
 	return sk, pk
 }
 
+// ValidateKeys checks that sk and pk are well formed and form a key pair,
+// e.g. after loading them from disk and before serving responses that
+// User1 would reject.
+func (mqat *MQAT) ValidateKeys(sk *MQATSecretKey, pk *MQATPublicKey) error {
+	if sk == nil || pk == nil || len(pk.seed_random_sys) != mqat.random_sys_seed_len/8 {
+		return ErrUOVKey
+	}
+	if mqat.nist != nil {
+		nist_pk, err := mqat.nist.PublicKeyFromSecret(sk.nist_sk)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(nist_pk, pk.nist_pk) {
+			return ErrKeyMismatch
+		}
+		return nil
+	}
+	if mqat.mayo != nil {
+		return mqat.mayo.ValidateSecretKey(sk.mayo_sk, pk.mayo_pk)
+	}
+	return mqat.uov.ValidateSecretKey(sk.uov_sk, pk.uov_pk)
+}
+
 func (mqat *MQAT) User0(pk *MQATPublicKey) ([]byte, []uint8, []uint8) {
-	t := make([]byte, 2*constants.LAMBDA/8)
+	F := mqat.Field
+	t := make([]byte, tokenLen)
 	_, err := rand.Read(t)
 	if err != nil {
 		logrus.Error("Could not sample t")
 		return nil, nil, nil
 	}
 
-	w := Nrand256(mqat.M, t)
+	w := domMQATTarget.sample(F, mqat.M, t)
 
 	z_star_seed := make([]byte, 2*constants.LAMBDA/8)
 	_, err = rand.Read(z_star_seed)
@@ -73,66 +186,268 @@ func (mqat *MQAT) User0(pk *MQATPublicKey) ([]byte, []uint8, []uint8) {
 		logrus.Error("Could not sample z* randomness")
 		return nil, nil, nil
 	}
-	z_star := Nrand256(mqat.M, z_star_seed)
+	z_star := domMQATZStar.sample(F, mqat.M, z_star_seed)
 	if z_star == nil {
 		logrus.Error("Could not sample z*")
 	}
-	R := Nrand128(math.Flen(mqat.M, mqat.M), pk.seed_random_sys)
-	w_star := math.MQR(R, z_star, mqat.M)
+	R, err := mqat.randomSystem(pk)
+	if err != nil {
+		logrus.Error("Could not expand random system")
+		return nil, nil, nil
+	}
+	w_star := R.Eval(z_star)
 
 	w_tilde := make([]uint8, mqat.M)
 	for i := 0; i < len(w_tilde); i++ {
-		w_tilde[i] = w[i] ^ w_star[i]
+		w_tilde[i] = F.Sub(w[i], w_star[i])
 	}
 
 	return t, z_star, w_tilde
 }
 
 func (mqat *MQAT) Sign0(sk *MQATSecretKey, query []byte) []uint8 {
+	if mqat.nist != nil {
+		return mqat.nist.SignTarget(query, sk.nist_sk)
+	}
+	if mqat.mayo != nil {
+		return mqat.mayo.Sign(query, sk.mayo_sk)
+	}
 	return mqat.uov.Sign(query, sk.uov_sk)
 }
 
+// Sign0Checked answers query like Sign0 and verifies the response under
+// the issuer's public key before releasing it, with the redundant checks of
+// UOV.SignChecked for plain UOV. A faulty response may leak the oil space
+// to the client, so a mismatch returns ErrFault and no response.
+func (mqat *MQAT) Sign0Checked(sk *MQATSecretKey, pk *MQATPublicKey, query []byte) ([]uint8, error) {
+	if sk == nil || pk == nil {
+		return nil, ErrUOVKey
+	}
+	if mqat.nist == nil && mqat.mayo == nil {
+		return mqat.uov.SignChecked(query, sk.uov_sk, pk.uov_pk)
+	}
+	resp := mqat.Sign0(sk, query)
+	if resp == nil {
+		return nil, ErrSignFailed
+	}
+	ok := false
+	if mqat.nist != nil {
+		P, err := mqat.nist.PublicMap(pk.nist_pk)
+		if err != nil {
+			return nil, err
+		}
+		ok = bytes.Equal(P.Eval(resp), query)
+	} else {
+		ok = mqat.mayo.Verify(query, resp, pk.mayo_pk)
+	}
+	if !ok {
+		return nil, ErrFault
+	}
+	return resp, nil
+}
+
 func (mqat *MQAT) User1(
 	pk *MQATPublicKey,
 	t []byte,
 	z_star []uint8,
 	resp []uint8,
 ) *MQATToken {
-	w := Nrand256(mqat.M, t)
-
-	P1i := pk.uov_pk.P1i
-	P2i := pk.uov_pk.P2i
-	P3i := pk.uov_pk.P3i
-	R := Nrand128(math.Flen(mqat.M, mqat.M), pk.seed_random_sys)
-	x := append(resp, z_star...)
-	if len(x) != mqat.N+mqat.M {
+	return mqat.User1With(pk, t, z_star, resp, mqat.mode)
+}
+
+// User1With finalizes the token with the given signing mode for its proof
+// instead of that of mqat. Hedged, the default, stays safe on clients with
+// a poor RNG.
+func (mqat *MQAT) User1With(
+	pk *MQATPublicKey,
+	t []byte,
+	z_star []uint8,
+	resp []uint8,
+	mode SigningMode,
+) *MQATToken {
+	return mqat.finalize(pk, t, z_star, resp, func(w []uint8, sk *MQDSSSecretKey) []byte {
+		return mqat.proof.SignWith(w, sk, mode)
+	})
+}
+
+// PrecomputeUser1 prepares the proof of the token value t ahead of the
+// issuer's response, e.g. while the device is idle, leaving User1 little
+// more than the commitments to the witness. It returns nil unless the
+// tokens are proven with MQDSS in the original format.
+func (mqat *MQAT) PrecomputeUser1(pk *MQATPublicKey, t []byte) *MQDSSPrecomputed {
+	mqdss, ok := mqat.proof.(*MQDSS)
+	if !ok {
 		return nil
 	}
-
-	w_prime := math.MQ(P1i, P2i, P3i, R, x, mqat.M, mqat.N)
-	for i := 0; i < mqat.M; i++ {
-		if w[i] != w_prime[i] {
-			return nil
-		}
+	PR, err := mqat.system(pk)
+	if err != nil {
+		return nil
 	}
+	_, mqdss_pk := mqdss.KeyPair(PR, nil, domMQATTarget.sample(mqat.Field, mqat.M, t))
+	return mqdss.Precompute(mqdss_pk, nil)
+}
 
-	mqdss_sk, _ := mqat.mqdss.KeyPair(P1i, P2i, P3i, R, x, w_prime)
-	sig := mqat.mqdss.Sign(w, mqdss_sk)
+// User1WithPrecomputed finalizes the token for t with material from
+// PrecomputeUser1, consuming it.
+func (mqat *MQAT) User1WithPrecomputed(
+	pk *MQATPublicKey,
+	t []byte,
+	z_star []uint8,
+	resp []uint8,
+	pre *MQDSSPrecomputed,
+) *MQATToken {
+	mqdss, ok := mqat.proof.(*MQDSS)
+	if !ok {
+		return nil
+	}
+	return mqat.finalize(pk, t, z_star, resp, func(w []uint8, sk *MQDSSSecretKey) []byte {
+		return mqdss.SignWithPrecomputed(w, sk, pre)
+	})
+}
 
-	mqat_token := new(MQATToken)
-	mqat_token.Token = t
-	mqat_token.MQDSSSignature = sig
+// NewRedeemer starts the interactive redemption of the token for t: the
+// holder proves knowledge of its witness over a live connection, with
+// fewer rounds and bytes than the proof of a finalized token. It returns nil
+// if resp is not a valid response for t.
+func (mqat *MQAT) NewRedeemer(pk *MQATPublicKey, t []byte, z_star, resp []uint8) *IDProver {
+	sk, _ := mqat.witness(pk, t, z_star, resp)
+	if sk == nil {
+		return nil
+	}
+	return mqat.id.NewProver(sk)
+}
 
-	return mqat_token
+// NewRedemptionVerifier starts the verification of the interactive
+// redemption of the token for t.
+func (mqat *MQAT) NewRedemptionVerifier(pk *MQATPublicKey, t []byte) *IDVerifier {
+	PR, err := mqat.system(pk)
+	if err != nil {
+		return nil
+	}
+	_, mqdss_pk := mqat.id.KeyPair(PR, nil, domMQATTarget.sample(mqat.Field, mqat.M, t))
+	return mqat.id.NewVerifier(mqdss_pk)
 }
 
 func (mqat *MQAT) Verify(pk *MQATPublicKey, token *MQATToken) bool {
-	w := Nrand256(mqat.M, token.Token)
-	R := Nrand128(math.Flen(mqat.M, mqat.M), pk.seed_random_sys)
-	_, mqdss_pk := mqat.mqdss.KeyPair(pk.uov_pk.P1i, pk.uov_pk.P2i, pk.uov_pk.P3i, R, nil, w)
-	return mqat.mqdss.Verify(w, token.MQDSSSignature, mqdss_pk)
+	w := domMQATTarget.sample(mqat.Field, mqat.M, token.Token)
+	PR, err := mqat.system(pk)
+	if err != nil {
+		return false
+	}
+	_, mqdss_pk := mqat.proof.KeyPair(PR, nil, w)
+	return mqat.proof.Verify(w, token.MQDSSSignature, mqdss_pk)
+}
+
+// TokenSize is the length in bytes of the tokens of mqat, or their maximum
+// length in the compact MQDSS format.
+func (mqat *MQAT) TokenSize() int {
+	return tokenLen + mqat.proof.SignatureSize()
+}
+
+// ExpectedTokenSize is the mean length in bytes of the tokens of mqat,
+// below TokenSize in the compact MQDSS format.
+func (mqat *MQAT) ExpectedTokenSize() int {
+	return tokenLen + mqat.proof.ExpectedSignatureSize()
+}
+
+// Challenge exports the statement behind a token, P(x) + R(z) = w for the
+// token value t, as an MQ Challenge instance for external solvers.
+func (mqat *MQAT) Challenge(pk *MQATPublicKey, t []byte) (*math.Challenge, error) {
+	PR, err := mqat.system(pk)
+	if err != nil {
+		return nil, err
+	}
+	return math.NewChallenge(PR, domMQATTarget.sample(mqat.Field, mqat.M, t))
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // Helpers
 ////////////////////////////////////////////////////////////////////////////////
+
+// tokenLen is the length of the token value t.
+const tokenLen = 2 * constants.LAMBDA / 8
+
+// issued is the number of variables of the issuer's map.
+func (mqat *MQAT) issued() int {
+	if mqat.mayo != nil {
+		return mqat.mayo.K * mqat.N
+	}
+	return mqat.N
+}
+
+func (mqat *MQAT) randomSystem(pk *MQATPublicKey) (*math.MQSystem, error) {
+	return domMQATRandomSystem.system(mqat.prg, mqat.Field, mqat.M, mqat.M, pk.seed_random_sys)
+}
+
+// system returns the combined map (x, z) -> P(x) + R(z) a token proves a
+// preimage for, P being the whipped map P* with MAYO.
+func (mqat *MQAT) system(pk *MQATPublicKey) (*math.MQSystem, error) {
+	var P *math.MQSystem
+	var err error
+	if mqat.nist != nil {
+		P, err = mqat.nist.PublicMap(pk.nist_pk)
+	} else if mqat.mayo != nil {
+		P, err = mqat.mayo.WhippedMap(pk.mayo_pk)
+	} else {
+		P, err = mqat.uov.PublicMap(pk.uov_pk)
+	}
+	if err != nil {
+		return nil, err
+	}
+	R, err := mqat.randomSystem(pk)
+	if err != nil {
+		return nil, err
+	}
+	return P.Concat(R)
+}
+
+// finalize checks the issuer's response and proves the token with sign.
+func (mqat *MQAT) finalize(
+	pk *MQATPublicKey,
+	t []byte,
+	z_star []uint8,
+	resp []uint8,
+	sign func(w []uint8, sk *MQDSSSecretKey) []byte,
+) *MQATToken {
+	mqdss_sk, w := mqat.witness(pk, t, z_star, resp)
+	if mqdss_sk == nil {
+		return nil
+	}
+	sig := sign(w, mqdss_sk)
+	mqdss_sk.Destroy()
+	if sig == nil {
+		return nil
+	}
+
+	mqat_token := new(MQATToken)
+	mqat_token.Token = t
+	mqat_token.MQDSSSignature = sig
+
+	return mqat_token
+}
+
+// witness checks the issuer's response for t and returns the witness as an
+// MQDSS secret key, with the target w.
+func (mqat *MQAT) witness(pk *MQATPublicKey, t []byte, z_star, resp []uint8) (*MQDSSSecretKey, []uint8) {
+	F := mqat.Field
+	w := domMQATTarget.sample(F, mqat.M, t)
+
+	PR, err := mqat.system(pk)
+	if err != nil {
+		return nil, nil
+	}
+	x := append(bytes.Clone(resp), z_star...)
+	if len(x) != mqat.issued()+mqat.M {
+		return nil, nil
+	}
+
+	w_prime := PR.Eval(x)
+	for i := 0; i < mqat.M; i++ {
+		if w[i] != w_prime[i] {
+			return nil, nil
+		}
+	}
+
+	mqdss_sk, _ := mqat.proof.KeyPair(PR, x, w_prime)
+	return mqdss_sk, w
+}
@@ -5,25 +5,51 @@ import (
 	"crypto/rand"
 	constants "mqat/const"
 	"mqat/math"
-
-	"golang.org/x/crypto/sha3"
 )
 
-func NewMQDSS(m, n, r, pk_seed_len, sk_seed_len int) *MQDSS {
+func NewMQDSS(m, n, r, pk_seed_len, sk_seed_len int, opts ...Option) *MQDSS {
+	return NewMQDSSOver(math.GF256, m, n, r, pk_seed_len, sk_seed_len, opts...)
+}
+
+func NewMQDSSOver(F math.Field, m, n, r, pk_seed_len, sk_seed_len int, opts ...Option) *MQDSS {
 	if m <= 0 || n <= 0 || r <= 0 || m > n {
 		return nil
 	}
+	o := newOptions(opts)
+	if !o.accepts("MQDSS", optExtension|optCompact|optInteractive|optPRG|optSigningMode) {
+		return nil
+	}
+	e := EstimateMQDSSExt(F.Order(), o.degree, m, n, r)
+	if o.interactive {
+		e.Soundness = InteractiveBits(pow(F.Order(), o.degree)-1, r)
+	}
+	if !secure("MQDSS", e, o) {
+		return nil
+	}
 	mqdss := new(MQDSS)
+	mqdss.Field = F
+	mqdss.Compact = o.compact
+	mqdss.Interactive = o.interactive
+	if o.degree > 1 {
+		E, err := math.NewExtension(F, o.degree)
+		if err != nil {
+			return nil
+		}
+		mqdss.Ext = E
+	}
 	mqdss.M = m
 	mqdss.N = n
 	mqdss.R = r
 	mqdss.PkSeedLen = pk_seed_len
 	mqdss.SkSeedLen = sk_seed_len
+	mqdss.PRG = o.prg
+	mqdss.Mode = o.mode
 	return mqdss
 }
 
 // TODO: handling errors better
 func (mqdss *MQDSS) KeyGen() (*MQDSSSecretKey, *MQDSSPublicKey) {
+	F := mqdss.Field
 	m := mqdss.M
 	n := mqdss.N - mqdss.M
 
@@ -35,30 +61,32 @@ func (mqdss *MQDSS) KeyGen() (*MQDSSSecretKey, *MQDSSPublicKey) {
 		return nil, nil
 	}
 
-	pk.R = Nrand128(math.Flen(m, m), seed_S_P_R[:mqdss.PkSeedLen/8])
-	P := Nrand128(math.Flen(m, n), seed_S_P_R[mqdss.PkSeedLen/8:2*mqdss.PkSeedLen/8])
-	P1len := m * (n - m) * (n - m + 1) / 2
-	P2len := m * m * (n - m)
-	pk.P1 = P[:P1len]
-	pk.P2 = P[P1len : P1len+P2len]
-	pk.P3 = P[P1len+P2len:]
+	R, err := domMQDSSR.system(mqdss.PRG, F, m, m, seed_S_P_R[:mqdss.PkSeedLen/8])
+	if err != nil {
+		return nil, nil
+	}
+	P, err := domMQDSSP.system(mqdss.PRG, F, m, n, seed_S_P_R[mqdss.PkSeedLen/8:2*mqdss.PkSeedLen/8])
+	if err != nil {
+		return nil, nil
+	}
+	pk.F, err = P.Concat(R)
+	if err != nil {
+		return nil, nil
+	}
 
-	sk.S = Nrand256(mqdss.N, seed_S_P_R[2*mqdss.PkSeedLen/8:])
-	pk.V = math.MQ(pk.P1, pk.P2, pk.P3, pk.R, sk.S, m, n)
+	sk.S = domMQDSSSecret.sample(F, mqdss.N, seed_S_P_R[2*mqdss.PkSeedLen/8:])
+	pk.V = pk.F.Eval(sk.S)
 	sk.Pk = pk
 
 	return sk, pk
 }
 
-func (mqdss *MQDSS) KeyPair(P1, P2, P3, R, S, V []uint8) (*MQDSSSecretKey, *MQDSSPublicKey) {
+func (mqdss *MQDSS) KeyPair(F *math.MQSystem, S, V []uint8) (*MQDSSSecretKey, *MQDSSPublicKey) {
 	sk := new(MQDSSSecretKey)
 	pk := new(MQDSSPublicKey)
 
 	sk.S = S
-	pk.P1 = P1
-	pk.P2 = P2
-	pk.P3 = P3
-	pk.R = R
+	pk.F = F
 	pk.V = V
 	sk.Pk = pk
 
@@ -66,176 +94,358 @@ func (mqdss *MQDSS) KeyPair(P1, P2, P3, R, S, V []uint8) (*MQDSSSecretKey, *MQDS
 }
 
 func (mqdss *MQDSS) Sign(message []uint8, sk *MQDSSSecretKey) []byte {
-	seedC := append(sk.Pk.P1, sk.Pk.P2...)
-	seedC = append(seedC, sk.Pk.P3...)
-	seedC = append(seedC, sk.Pk.R...)
-	seedC = append(seedC, message...)
-	C := H(seedC)
-	seedD := append(C[:], message...)
-	D := H(seedD)
-	seed := make([]byte, mqdss.SkSeedLen)
-	_, err := rand.Read(seed)
-	if err != nil {
+	return mqdss.SignWith(message, sk, mqdss.Mode)
+}
+
+// SignWith signs with the given mode instead of that of mqdss.
+func (mqdss *MQDSS) SignWith(message []uint8, sk *MQDSSSecretKey, mode SigningMode) []byte {
+	return mqdss.sign(message, sk, mode, nil)
+}
+
+func (mqdss *MQDSS) Verify(message []uint8, sig []byte, pk *MQDSSPublicKey) bool {
+	return mqdss.verify(message, sig, pk, nil)
+}
+
+// SignatureSize is the length in bytes of the signatures of mqdss, or their
+// maximum length in the compact format.
+func (mqdss *MQDSS) SignatureSize() int {
+	F := mqdss.Field
+	k := mqdss.degree()
+	if mqdss.Compact {
+		return 3*constants.HASH_BYTES + mqdss.R*(F.PackedLen(mqdss.N)+
+			F.PackedLen((mqdss.N+mqdss.M)*k)+constants.HASH_BYTES)
+	}
+	return 2*constants.HASH_BYTES +
+		F.PackedLen(mqdss.R*mqdss.N*k) + F.PackedLen(mqdss.R*mqdss.M*k) +
+		mqdss.R*(F.PackedLen(mqdss.N)+constants.HASH_BYTES)
+}
+
+// ExpectedSignatureSize is the mean length in bytes of the signatures of
+// mqdss. In the compact format half of the rounds reveal r1, t1 and e1 on
+// average, the others being covered by the seeds of the tree, and the Merkle
+// opening is the unopened commitment of every round.
+func (mqdss *MQDSS) ExpectedSignatureSize() int {
+	if !mqdss.Compact {
+		return mqdss.SignatureSize()
+	}
+	F := mqdss.Field
+	k := mqdss.degree()
+	responses := float64(mqdss.R) / 2 * float64(F.PackedLen(mqdss.N)+F.PackedLen((mqdss.N+mqdss.M)*k))
+	seeds := expectedCover(mqdss.R) * constants.LAMBDA / 8
+	return 3*constants.HASH_BYTES + int(responses+seeds+0.5) + mqdss.R*constants.HASH_BYTES
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// sign and verify record their transcript in tr unless it is nil.
+func (mqdss *MQDSS) sign(message []uint8, sk *MQDSSSecretKey, mode SigningMode, tr *Transcript) []byte {
+	if mqdss.Interactive || len(sk.S) != mqdss.N {
 		return nil
 	}
-	seed = append(seed, D[:]...)
-	r0t0e0 := Nrand256((2*mqdss.N+mqdss.M)*mqdss.R, seed)
-	r0 := r0t0e0[:mqdss.R*mqdss.N]
-	r1 := make([]uint8, len(r0))
-	t0 := r0t0e0[uint(mqdss.R)*uint(mqdss.N) : 2*uint(mqdss.R)*uint(mqdss.N)]
-	t1 := make([]uint8, len(t0))
-	e0 := r0t0e0[2*uint(mqdss.R)*uint(mqdss.N):]
-	e1 := make([]uint8, len(e0))
-	G := make([]uint8, 0)
-
-	sk_gf256 := sk.S
-	for i := 0; i < mqdss.R; i++ {
-		for j := 0; j < mqdss.N; j++ {
-			r1ij := sk_gf256[j] ^ r0[j+i*int(mqdss.N)]
-			r1[j+i*int(mqdss.N)] = r1ij
-		}
-		G = append(G,
-			math.G(sk.Pk.P1, sk.Pk.P2, sk.Pk.P3, sk.Pk.R,
-				t0[i*int(mqdss.N):(i+1)*int(mqdss.N)],
-				r1[i*int(mqdss.N):(i+1)*int(mqdss.N)],
-				mqdss.M, mqdss.N-mqdss.M)...)
+	F := mqdss.Field
+	C := domMQDSSC.hash(sk.Pk.F.Field.Pack(sk.Pk.F.Coeffs), message)
+	D := domMQDSSD.hash(C, message)
+	packed := F.Pack(sk.S)
+	seed := mode.seed(domMQDSSSeed, mqdss.SkSeedLen/8, packed, D)
+	Wipe(packed)
+	if seed == nil {
+		return nil
 	}
-	for i := 0; i < mqdss.R*mqdss.M; i++ {
-		gi := G[i] ^ e0[i]
-		G[i] = gi
+	defer Wipe(seed)
+	if mqdss.Compact {
+		return mqdss.signCompact(C, D, seed, sk, tr)
 	}
 
-	c := make([]byte, 0)
-	for i := 0; i < mqdss.R; i++ {
-		c = append(c, com0(r0[i*mqdss.N:(i+1)*mqdss.N], t0[i*mqdss.N:(i+1)*mqdss.N], e0[i*mqdss.M:(i+1)*mqdss.M])...)
-		c = append(c, com1(r1[i*mqdss.N:(i+1)*mqdss.N], G[i*mqdss.M:(i+1)*mqdss.M])...)
-	}
-	sigma0 := H(c)
-	h0 := append(D[:], sigma0[:]...)
-
-	alphas := Nrand256(mqdss.R, h0)
-	for i := 0; i < mqdss.R; i++ {
-		for j := 0; j < int(mqdss.N); j++ {
-			t1ij := math.Mul(alphas[i], r0[i*mqdss.N+j]) ^ t0[i*mqdss.N+j]
-			t1[i*mqdss.N+j] = t1ij
-		}
-		Fr0 := math.MQ(sk.Pk.P1, sk.Pk.P2, sk.Pk.P3, sk.Pk.R,
-			r0[i*int(mqdss.N):(i+1)*int(mqdss.N)],
-			mqdss.M, mqdss.N-mqdss.M)
-		for j := 0; j < mqdss.M; j++ {
-			e1ij := math.Mul(alphas[i], Fr0[j]) ^ e0[i*mqdss.M+j]
-			e1[i*mqdss.M+j] = e1ij
-		}
+	rounds := mqdss.prepare(sk.Pk, seed, D)
+	defer wipeRounds(rounds)
+	for _, r := range rounds {
+		mqdss.commit(sk, r)
+	}
+	return mqdss.finish(C, D, sk.Pk, rounds, tr)
+}
+
+func (mqdss *MQDSS) verify(message []uint8, sig []byte, pk *MQDSSPublicKey, tr *Transcript) bool {
+	if mqdss.Interactive {
+		return false
+	}
+	if mqdss.Compact {
+		return mqdss.verifyCompact(message, sig, pk, tr)
+	}
+	F := mqdss.Field
+	N, M, k := mqdss.N, mqdss.M, mqdss.degree()
+	lenT1 := F.PackedLen(mqdss.R * N * k)
+	lenE1 := F.PackedLen(mqdss.R * M * k)
+	lenR := F.PackedLen(N)
+	offset := 2*constants.HASH_BYTES + lenT1 + lenE1
+	if len(sig) != mqdss.SignatureSize() {
+		return false
+	}
+
+	C := bytes.Clone(sig[:constants.HASH_BYTES])
+	D := domMQDSSD.hash(C, message)
+
+	sigma0 := bytes.Clone(sig[constants.HASH_BYTES : 2*constants.HASH_BYTES])
+	sigma1 := bytes.Clone(sig[2*constants.HASH_BYTES : offset])
+	sigma2 := bytes.Clone(sig[offset:])
+	t1s := F.Unpack(sigma1[:lenT1], mqdss.R*N*k)
+	e1s := F.Unpack(sigma1[lenT1:], mqdss.R*M*k)
+	if t1s == nil || e1s == nil {
+		return false
 	}
-	sigma1 := append(t1, e1...)
-	h1 := sha3.NewShake256()
-	tohash := append(h0, alphas...)
-	tohash = append(tohash, sigma1...)
-	h1.Write(tohash)
+	tr.start(C, D, sigma0, mqdss.R)
+
+	h0 := append(bytes.Clone(D), sigma0...)
+	alphas := mqdss.challenges(h0)
+	tr.challenges(alphas, nil)
+	h1 := domMQDSSBits.xof(h0, alphas, sigma1)
 	shakeBlock := make([]byte, h1.BlockSize())
-	sigma2 := make([]byte, 0)
+	c := make([]byte, 0)
 	for i := 0; i < mqdss.R; {
 		h1.Read(shakeBlock)
 		for _, v := range shakeBlock {
+			r_offset := i * (lenR + constants.HASH_BYTES)
+			c_offset := r_offset + lenR
+			r_ch := F.Unpack(sigma2[r_offset:c_offset], N)
+			if r_ch == nil {
+				return false
+			}
+			c_ch := bytes.Clone(sigma2[c_offset : c_offset+constants.HASH_BYTES])
+			t1 := t1s[i*N*k : (i+1)*N*k]
+			e1 := e1s[i*M*k : (i+1)*M*k]
+			alpha := alphas[i*k : (i+1)*k]
+
 			b := v & 1
 			if b == 0 {
-				sigma2 = append(sigma2, r0[i*int(mqdss.N):(i+1)*int(mqdss.N)]...)
-				sigma2 = append(sigma2, c[constants.HASH_BYTES*(2*i+1):constants.HASH_BYTES*(2*(i+1))]...)
+				c = append(c, mqdss.check0(pk, alpha, r_ch, t1, e1)...)
+				c = append(c, c_ch...)
 			} else {
-				sigma2 = append(sigma2, r1[i*int(mqdss.N):(i+1)*int(mqdss.N)]...)
-				sigma2 = append(sigma2, c[constants.HASH_BYTES*(2*i):constants.HASH_BYTES*(2*i+1)]...)
+				c = append(c, c_ch...)
+				c = append(c, mqdss.check1(pk, alpha, r_ch, t1, e1)...)
 			}
+			tr.round(i, b, c[len(c)-2*constants.HASH_BYTES:len(c)-constants.HASH_BYTES], c[len(c)-constants.HASH_BYTES:])
 			i++
 			if i >= int(mqdss.R) {
 				break
 			}
 		}
 	}
-	sig := append(C[:], sigma0[:]...)
-	sig = append(sig, sigma1...)
-	sig = append(sig, sigma2...)
-	return sig
+	sigma0_prime := domMQDSSSigma0.hash(c)
+	tr.recomputed(sigma0_prime, nil)
+	return bytes.Equal(sigma0, sigma0_prime)
 }
 
-func (mqdss *MQDSS) Verify(message []uint8, sig []byte, pk *MQDSSPublicKey) bool {
-	C := bytes.Clone(sig[:constants.HASH_BYTES])
-	tohash := append(C, message...)
-	D := H(tohash)
+// degree is the extension degree of the challenges and masks.
+func (mqdss *MQDSS) degree() int {
+	if mqdss.Ext == nil {
+		return 1
+	}
+	return mqdss.Ext.K
+}
 
-	sigma0 := bytes.Clone(sig[constants.HASH_BYTES : 2*constants.HASH_BYTES])
-	offset := 2*constants.HASH_BYTES + mqdss.R*(mqdss.M+mqdss.N)
-	sigma1 := bytes.Clone(sig[2*constants.HASH_BYTES : offset])
-	sigma2 := bytes.Clone(sig[offset:])
+// challenges expands the R nonzero challenges alpha from h0.
+func (mqdss *MQDSS) challenges(h0 []byte) []uint8 {
+	E := mqdss.Ext
+	if E == nil {
+		return domMQDSSAlpha.nonZero(mqdss.Field, mqdss.R, h0)
+	}
+	return domMQDSSAlpha.nonZeroExt(E, mqdss.R, h0)
+}
+
+// mqdssRound is the prover state of one round.
+type mqdssRound struct {
+	r0, r1 []uint8
+	t0, t1 []uint8
+	e0, e1 []uint8
+	fr0    []uint8 // F(r0)
+	c0, c1 []byte
+}
+
+// prepare expands the randomness (r0, t0, e0) of every round from the seed
+// and opens the rounds; r0 is over the base field, t0 and e0 over the
+// extension if any.
+func (mqdss *MQDSS) prepare(pk *MQDSSPublicKey, seed, D []byte) []*mqdssRound {
+	N, M, k := mqdss.N, mqdss.M, mqdss.degree()
+	r0t0e0 := domMQDSSRandomness.sample(mqdss.Field, (N+k*(N+M))*mqdss.R, seed, D)
+	rounds := make([]*mqdssRound, mqdss.R)
+	for i := range rounds {
+		rounds[i] = mqdss.open(pk,
+			r0t0e0[i*N:(i+1)*N],
+			r0t0e0[mqdss.R*N+i*N*k:mqdss.R*N+(i+1)*N*k],
+			r0t0e0[mqdss.R*N*(1+k)+i*M*k:mqdss.R*N*(1+k)+(i+1)*M*k])
+	}
+	return rounds
+}
+
+// open computes the parts of a round that do not depend on the secret: the
+// commitment c0 and F(r0).
+func (mqdss *MQDSS) open(pk *MQDSSPublicKey, r0, t0, e0 []uint8) *mqdssRound {
+	return &mqdssRound{r0: r0, t0: t0, e0: e0, fr0: pk.F.Eval(r0), c0: com0(r0, t0, e0)}
+}
+
+// commit splits the secret as r0 + r1 and commits to the masked first
+// message of the second share.
+func (mqdss *MQDSS) commit(sk *MQDSSSecretKey, r *mqdssRound) {
+	F := mqdss.Field
+	r.r1 = make([]uint8, mqdss.N)
+	for j := range r.r1 {
+		r.r1[j] = F.Sub(sk.S[j], r.r0[j])
+	}
+	G := mqdss.polar(sk.Pk.F, r.t0, r.r1)
+	for j := range G {
+		G[j] = F.Add(G[j], r.e0[j])
+	}
+	r.c1 = com1(r.r1, G)
+}
+
+// finish hashes the commitments of the rounds and completes the signature
+// from the challenges on.
+func (mqdss *MQDSS) finish(C, D []byte, pk *MQDSSPublicKey, rounds []*mqdssRound, tr *Transcript) []byte {
+	F := mqdss.Field
+	c := make([]byte, 0, 2*mqdss.R*constants.HASH_BYTES)
+	for _, r := range rounds {
+		c = append(c, r.c0...)
+		c = append(c, r.c1...)
+	}
+	sigma0 := domMQDSSSigma0.hash(c)
+	tr.start(C, D, sigma0, mqdss.R)
+	h0 := append(bytes.Clone(D), sigma0...)
 
-	h0 := append(D[:], sigma0...)
-	alphas := Nrand256(mqdss.R, h0)
-	h1 := sha3.NewShake256()
-	tohash = append(h0, alphas...)
-	tohash = append(tohash, sigma1...)
-	h1.Write(tohash)
+	alphas := mqdss.challenges(h0)
+	tr.challenges(alphas, nil)
+	sigma1 := mqdss.respond(pk, rounds, alphas)
+	h1 := domMQDSSBits.xof(h0, alphas, sigma1)
 	shakeBlock := make([]byte, h1.BlockSize())
-	c := make([]byte, 0)
+	sigma2 := make([]byte, 0)
 	for i := 0; i < mqdss.R; {
 		h1.Read(shakeBlock)
 		for _, v := range shakeBlock {
-			r_offset := i * (mqdss.N + constants.HASH_BYTES)
-			c_offset := r_offset + mqdss.N
-			r_ch := bytes.Clone(sigma2[r_offset:c_offset])
-			c_ch := bytes.Clone(sigma2[c_offset : c_offset+constants.HASH_BYTES])
-			t_offset := i * mqdss.N
-			t1 := sigma1[t_offset : t_offset+mqdss.N]
-			e_offset := mqdss.R*mqdss.N + i*mqdss.M
-			e1 := sigma1[e_offset : e_offset+mqdss.M]
-
 			b := v & 1
 			if b == 0 {
-				x := make([]uint8, mqdss.N)
-				for j := 0; j < mqdss.N; j++ {
-					xj := math.Mul(alphas[i], uint8(r_ch[j])) ^ uint8(t1[j])
-					x[j] = xj
-				}
-				y := math.MQ(pk.P1, pk.P2, pk.P3, pk.R, r_ch, mqdss.M, mqdss.N-mqdss.M)
-				for j := 0; j < int(mqdss.M); j++ {
-					yj := math.Mul(alphas[i], y[j]) ^ uint8(e1[j])
-					y[j] = yj
-				}
-				c0 := com0(r_ch, x, y)
-				c = append(c, c0...)
-				c = append(c, c_ch...)
+				sigma2 = append(sigma2, F.Pack(rounds[i].r0)...)
+				sigma2 = append(sigma2, rounds[i].c1...)
 			} else {
-				y := math.MQ(pk.P1, pk.P2, pk.P3, pk.R, r_ch, mqdss.M, mqdss.N-mqdss.M)
-				z := math.G(pk.P1, pk.P2, pk.P3, pk.R, r_ch, t1, mqdss.M, mqdss.N-mqdss.M)
-				for j := 0; j < int(mqdss.M); j++ {
-					yj := math.Mul(alphas[i], pk.V[j]^y[j]) ^ z[j] ^ uint8(e1[j])
-					y[j] = yj
-				}
-				c = append(c, c_ch...)
-				c1 := com1(r_ch, y)
-				c = append(c, c1...)
+				sigma2 = append(sigma2, F.Pack(rounds[i].r1)...)
+				sigma2 = append(sigma2, rounds[i].c0...)
 			}
+			tr.round(i, b, rounds[i].c0, rounds[i].c1)
 			i++
 			if i >= int(mqdss.R) {
 				break
 			}
 		}
 	}
-	sigma0_prime := H(c)
-	return bytes.Equal(sigma0, sigma0_prime[:])
+	tr.recomputed(sigma0, nil)
+	sig := append(bytes.Clone(C), sigma0...)
+	sig = append(sig, sigma1...)
+	sig = append(sig, sigma2...)
+	return sig
 }
 
-////////////////////////////////////////////////////////////////////////////////
-// Helpers
-////////////////////////////////////////////////////////////////////////////////
+// respond computes t1 = alpha r0 - t0 and e1 = alpha F(r0) - e0 for every
+// round and returns them packed as sigma1.
+func (mqdss *MQDSS) respond(pk *MQDSSPublicKey, rounds []*mqdssRound, alphas []uint8) []byte {
+	k := mqdss.degree()
+	for i, r := range rounds {
+		mqdss.respondRound(pk, r, alphas[i*k:(i+1)*k])
+	}
+	return mqdss.sigma1(rounds)
+}
+
+func (mqdss *MQDSS) respondRound(pk *MQDSSPublicKey, r *mqdssRound, alpha []uint8) {
+	F := mqdss.Field
+	r.t1 = mqdss.scale(alpha, r.r0)
+	for j := range r.t1 {
+		r.t1[j] = F.Sub(r.t1[j], r.t0[j])
+	}
+	if r.fr0 == nil {
+		r.fr0 = pk.F.Eval(r.r0)
+	}
+	r.e1 = mqdss.scale(alpha, r.fr0)
+	for j := range r.e1 {
+		r.e1[j] = F.Sub(r.e1[j], r.e0[j])
+	}
+}
+
+func (mqdss *MQDSS) sigma1(rounds []*mqdssRound) []byte {
+	k := mqdss.degree()
+	t1 := make([]uint8, 0, mqdss.R*mqdss.N*k)
+	e1 := make([]uint8, 0, mqdss.R*mqdss.M*k)
+	for _, r := range rounds {
+		t1 = append(t1, r.t1...)
+		e1 = append(e1, r.e1...)
+	}
+	return append(mqdss.Field.Pack(t1), mqdss.Field.Pack(e1)...)
+}
+
+// check0 recomputes c0 = com0(r0, alpha r0 - t1, alpha F(r0) - e1).
+func (mqdss *MQDSS) check0(pk *MQDSSPublicKey, alpha, r0, t1, e1 []uint8) []byte {
+	F := mqdss.Field
+	x := mqdss.scale(alpha, r0)
+	for j := range x {
+		x[j] = F.Sub(x[j], t1[j])
+	}
+	y := mqdss.scale(alpha, pk.F.Eval(r0))
+	for j := range y {
+		y[j] = F.Sub(y[j], e1[j])
+	}
+	return com0(r0, x, y)
+}
+
+// check1 recomputes c1 = com1(r1, alpha (v - F(r1)) - G(t1, r1) - e1).
+func (mqdss *MQDSS) check1(pk *MQDSSPublicKey, alpha, r1, t1, e1 []uint8) []byte {
+	F := mqdss.Field
+	y := pk.F.Eval(r1)
+	for j := range y {
+		y[j] = F.Sub(pk.V[j], y[j])
+	}
+	y = mqdss.scale(alpha, y)
+	z := mqdss.polar(pk.F, t1, r1)
+	for j := range y {
+		y[j] = F.Sub(F.Sub(y[j], z[j]), e1[j])
+	}
+	return com1(r1, y)
+}
+
+// scale returns alpha v for alpha in the extension and v over the base
+// field.
+func (mqdss *MQDSS) scale(alpha, v []uint8) []uint8 {
+	F := mqdss.Field
+	k := len(alpha)
+	res := make([]uint8, len(v)*k)
+	for j, vj := range v {
+		for l, al := range alpha {
+			res[j*k+l] = F.Mul(al, vj)
+		}
+	}
+	return res
+}
+
+// polar returns G(t, r) for t over the extension and r over the base field,
+// one coordinate of the extension at a time since G is bilinear.
+func (mqdss *MQDSS) polar(P *math.MQSystem, t, r []uint8) []uint8 {
+	k := mqdss.degree()
+	if k == 1 {
+		return P.Polar(t, r)
+	}
+	res := make([]uint8, P.M*k)
+	tl := make([]uint8, len(r))
+	for l := 0; l < k; l++ {
+		for j := range tl {
+			tl[j] = t[j*k+l]
+		}
+		for i, g := range P.Polar(tl, r) {
+			res[i*k+l] = g
+		}
+	}
+	return res
+}
 
 func com0(r0, t0, e0 []uint8) []byte {
-	tmp := append(bytes.Clone(t0), bytes.Clone(e0)...)
-	m := append(bytes.Clone(r0), tmp...)
-	digest := sha3.Sum256(bytes.Clone(m))
-	return digest[:]
+	return domMQDSSCom0.hash(r0, t0, e0)
 }
 
 func com1(r1, gx []uint8) []byte {
-	m := append(bytes.Clone(r1), bytes.Clone(gx)...)
-	digest := sha3.Sum256(bytes.Clone(m))
-	return digest[:]
+	return domMQDSSCom1.hash(r1, gx)
 }
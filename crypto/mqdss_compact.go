@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"bytes"
+	constants "mqat/const"
+)
+
+// Compact MQDSS signature format. The randomness (r0, t0, e0) of every round
+// is expanded from a leaf of a seed tree, so the rounds opened with b = 0 are
+// revealed as the few seeds covering them and their responses t1, e1 are
+// recomputed by the verifier. The 2R commitments are the leaves of a Merkle
+// tree whose root is sigma0. The challenge bits are derived from h1, the
+// hash of the responses, which is sent so that the verifier can parse the
+// signature before recomputing it:
+//
+//	C | sigma0 | h1 | (r1, t1, e1) for rounds with b = 1 | seeds | Merkle opening
+
+func (mqdss *MQDSS) signCompact(C, D, seed []byte, sk *MQDSSSecretKey, tr *Transcript) []byte {
+	F := mqdss.Field
+	N, M, k := mqdss.N, mqdss.M, mqdss.degree()
+
+	tree := newSeedTree(domMQDSSRoot.bytes(constants.LAMBDA/8, seed, D), D, mqdss.R)
+	defer tree.wipe()
+	rounds := make([]*mqdssRound, mqdss.R)
+	defer wipeRounds(rounds)
+	leaves := make([][]byte, 0, 2*mqdss.R)
+	for i := range rounds {
+		r0t0e0 := domMQDSSLeaf.sample(F, N+k*(N+M), tree.leaf(i))
+		rounds[i] = mqdss.open(sk.Pk, r0t0e0[:N], r0t0e0[N:N+N*k], r0t0e0[N+N*k:])
+		mqdss.commit(sk, rounds[i])
+		leaves = append(leaves, rounds[i].c0, rounds[i].c1)
+	}
+	merkle := newMerkleTree(leaves)
+	sigma0 := merkle.root()
+	tr.start(C, D, sigma0, mqdss.R)
+	h0 := append(bytes.Clone(D), sigma0...)
+
+	alphas := mqdss.challenges(h0)
+	sigma1 := mqdss.respond(sk.Pk, rounds, alphas)
+	h1 := domMQDSSH1.hash(h0, alphas, sigma1)
+	tr.challenges(alphas, h1)
+	tr.recomputed(sigma0, h1)
+
+	bits := domMQDSSBits.bytes(mqdss.R, h1)
+	opened := make([]bool, mqdss.R)
+	known := make([]bool, 2*mqdss.R)
+	sig := append(append(bytes.Clone(C), sigma0...), h1...)
+	for i, r := range rounds {
+		opened[i] = bits[i]&1 == 0
+		known[2*i] = opened[i]
+		known[2*i+1] = !opened[i]
+		tr.round(i, bits[i]&1, r.c0, r.c1)
+		if !opened[i] {
+			sig = append(sig, F.Pack(r.r1)...)
+			sig = append(sig, F.Pack(append(bytes.Clone(r.t1), r.e1...))...)
+		}
+	}
+	for _, s := range tree.reveal(opened) {
+		sig = append(sig, s...)
+	}
+	for _, node := range merkle.open(known) {
+		sig = append(sig, node...)
+	}
+	return sig
+}
+
+func (mqdss *MQDSS) verifyCompact(message []uint8, sig []byte, pk *MQDSSPublicKey, tr *Transcript) bool {
+	F := mqdss.Field
+	N, M, k := mqdss.N, mqdss.M, mqdss.degree()
+	lenR := F.PackedLen(N)
+	lenTE := F.PackedLen((N + M) * k)
+	if len(sig) < 3*constants.HASH_BYTES {
+		return false
+	}
+
+	C := sig[:constants.HASH_BYTES]
+	D := domMQDSSD.hash(C, message)
+	sigma0 := sig[constants.HASH_BYTES : 2*constants.HASH_BYTES]
+	h1 := sig[2*constants.HASH_BYTES : 3*constants.HASH_BYTES]
+	rest := sig[3*constants.HASH_BYTES:]
+
+	bits := domMQDSSBits.bytes(mqdss.R, h1)
+	opened := make([]bool, mqdss.R)
+	rounds := make([]*mqdssRound, mqdss.R)
+	for i := range rounds {
+		opened[i] = bits[i]&1 == 0
+		if opened[i] {
+			continue
+		}
+		if len(rest) < lenR+lenTE {
+			return false
+		}
+		r1 := F.Unpack(rest[:lenR], N)
+		te1 := F.Unpack(rest[lenR:lenR+lenTE], (N+M)*k)
+		if r1 == nil || te1 == nil {
+			return false
+		}
+		rounds[i] = &mqdssRound{r1: r1, t1: te1[:N*k], e1: te1[N*k:]}
+		rest = rest[lenR+lenTE:]
+	}
+	seedLen := constants.LAMBDA / 8
+	cover := len(coveringNodes(opened))
+	if len(rest) < cover*seedLen || (len(rest)-cover*seedLen)%constants.HASH_BYTES != 0 {
+		return false
+	}
+	revealed := make([][]byte, cover)
+	for j := range revealed {
+		revealed[j] = rest[j*seedLen : (j+1)*seedLen]
+	}
+	rest = rest[cover*seedLen:]
+	opening := make([][]byte, len(rest)/constants.HASH_BYTES)
+	for j := range opening {
+		opening[j] = rest[j*constants.HASH_BYTES : (j+1)*constants.HASH_BYTES]
+	}
+	seeds := recoverSeeds(revealed, opened, D[:])
+	if seeds == nil {
+		return false
+	}
+
+	tr.start(C, D, sigma0, mqdss.R)
+	h0 := append(bytes.Clone(D[:]), sigma0...)
+	alphas := mqdss.challenges(h0)
+	tr.challenges(alphas, h1)
+	leaves := make([][]byte, 2*mqdss.R)
+	for i, r := range rounds {
+		alpha := alphas[i*k : (i+1)*k]
+		if opened[i] {
+			r0t0e0 := domMQDSSLeaf.sample(F, N+k*(N+M), seeds[i])
+			r = &mqdssRound{r0: r0t0e0[:N], t0: r0t0e0[N : N+N*k], e0: r0t0e0[N+N*k:]}
+			mqdss.respondRound(pk, r, alpha)
+			rounds[i] = r
+			leaves[2*i] = com0(r.r0, r.t0, r.e0)
+		} else {
+			leaves[2*i+1] = mqdss.check1(pk, alpha, r.r1, r.t1, r.e1)
+		}
+		tr.round(i, bits[i]&1, leaves[2*i], leaves[2*i+1])
+	}
+	root := merkleRoot(leaves, opening)
+	h1_prime := domMQDSSH1.hash(h0, alphas, mqdss.sigma1(rounds))
+	tr.recomputed(root, h1_prime)
+	return root != nil && bytes.Equal(root, sigma0) && bytes.Equal(h1, h1_prime)
+}
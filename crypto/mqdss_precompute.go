@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"sync"
+)
+
+// Offline/online MQDSS signing. Precompute samples the randomness of every
+// round afresh and does the work that does not depend on the message: the
+// commitments c0 and F(r0) and, if the witness is already known, the
+// commitments c1. SignWithPrecomputed then only hashes, derives the
+// challenges and computes the responses, which are linear except for the
+// commitments c1 still missing. The compact format is not supported, its
+// seed tree being salted with the message digest.
+//
+// Precomputed material must be used at most once: two signatures from the
+// same (r0, t0, e0) with different challenges reveal the witness, so it is
+// consumed by the first call of SignWithPrecomputed.
+
+// MQDSSPrecomputed is the message-independent commitment material of one
+// signature.
+type MQDSSPrecomputed struct {
+	mu      sync.Mutex
+	used    bool
+	pk      *MQDSSPublicKey
+	witness []uint8 // the witness the commitments c1 are for, nil if not yet known
+	rounds  []*mqdssRound
+}
+
+// Precompute prepares the material of one signature under pk with fresh
+// randomness, including the commitments of the witness if sk is not nil. It
+// returns nil for the compact format or if the RNG fails.
+func (mqdss *MQDSS) Precompute(pk *MQDSSPublicKey, sk *MQDSSSecretKey) *MQDSSPrecomputed {
+	if mqdss.Compact || mqdss.Interactive || (sk != nil && (len(sk.S) != mqdss.N || !samePublicKey(pk, sk.Pk))) {
+		return nil
+	}
+	seed := make([]byte, mqdss.SkSeedLen/8)
+	if _, err := rand.Read(seed); err != nil {
+		return nil
+	}
+	pre := &MQDSSPrecomputed{pk: pk, rounds: mqdss.prepare(pk, seed, nil)}
+	if sk != nil {
+		for _, r := range pre.rounds {
+			mqdss.commit(sk, r)
+		}
+		pre.witness = bytes.Clone(sk.S)
+	}
+	return pre
+}
+
+// SignWithPrecomputed signs message with sk from material precomputed for
+// its public key, consuming it. It returns nil if pre was already used or
+// does not match sk.
+func (mqdss *MQDSS) SignWithPrecomputed(message []uint8, sk *MQDSSSecretKey, pre *MQDSSPrecomputed) []byte {
+	if pre == nil || mqdss.Compact || mqdss.Interactive {
+		return nil
+	}
+	pre.mu.Lock()
+	defer pre.mu.Unlock()
+	if pre.used || len(pre.rounds) != mqdss.R || len(sk.S) != mqdss.N || !samePublicKey(pre.pk, sk.Pk) ||
+		(pre.witness != nil && !bytes.Equal(pre.witness, sk.S)) {
+		return nil
+	}
+	pre.used = true
+	if pre.witness == nil {
+		for _, r := range pre.rounds {
+			mqdss.commit(sk, r)
+		}
+	}
+	C := domMQDSSC.hash(sk.Pk.F.Field.Pack(sk.Pk.F.Coeffs), message)
+	D := domMQDSSD.hash(C, message)
+	sig := mqdss.finish(C, D, sk.Pk, pre.rounds, nil)
+	wipeRounds(pre.rounds)
+	Wipe(pre.witness)
+	pre.rounds, pre.witness = nil, nil
+	return sig
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+func samePublicKey(a, b *MQDSSPublicKey) bool {
+	return a != nil && b != nil && a.F.Equal(b.F) && bytes.Equal(a.V, b.V)
+}
@@ -0,0 +1,439 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	constants "mqat/const"
+	"mqat/math"
+)
+
+// MPC-in-the-head proof of knowledge of x with F(x) = v, in the style of
+// MQOM, as an alternative to MQDSS. Keys are those of MQDSS.
+//
+// Each of the Tau repetitions additively shares x, a random mask a in K^N and
+// c = <a, A x> among 2^D parties, where K is the extension of degree k of the
+// field and A = sum_i gamma_i U_i batches the equations with a first
+// challenge gamma in K^M. The shares of every party come from a leaf of a
+// seed tree; the last party gets corrections dx and dc. With a second
+// challenge eps in K*, the parties open alpha = eps x + a and check that
+// <alpha, A x> - eps <gamma, v> - c = eps (x^T A x - <gamma, v>) vanishes.
+// Parties are grouped in the D pairs of main parties of the hypercube, so
+// that only D+1 of them are emulated. The third challenge hides one party,
+// whose seed is the only one not revealed.
+//
+//	salt | h3 | for each repetition: seeds | com | dx | dc | alpha
+
+func NewMQOM(m, n, tau, d int, opts ...Option) *MQOM {
+	return NewMQOMOver(math.GF256, m, n, tau, d, opts...)
+}
+
+func NewMQOMOver(F math.Field, m, n, tau, d int, opts ...Option) *MQOM {
+	if m <= 0 || n <= 0 || m > n || tau <= 0 || d <= 0 || d > 16 {
+		return nil
+	}
+	o := newOptions(opts)
+	if !o.accepts("MQOM", optExtension|optPRG|optSigningMode) {
+		return nil
+	}
+	if !secure("MQOM", EstimateMQOM(F.Order(), o.degree, m, n, tau, d), o) {
+		return nil
+	}
+	E, err := math.NewExtension(F, o.degree)
+	if err != nil {
+		return nil
+	}
+	mqom := new(MQOM)
+	mqom.Field = F
+	mqom.Ext = E
+	mqom.M = m
+	mqom.N = n
+	mqom.Tau = tau
+	mqom.D = d
+	mqom.PRG = o.prg
+	mqom.Mode = o.mode
+	return mqom
+}
+
+// KeyGen samples a random system and its preimage, with seeds of the
+// MQDSS lengths.
+func (mqom *MQOM) KeyGen() (*MQDSSSecretKey, *MQDSSPublicKey) {
+	pk_seed_len := constants.MQDSS_PK_SEED_LEN / 8
+	seed := make([]byte, pk_seed_len+constants.MQDSS_SK_SEED_LEN/8)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, nil
+	}
+	P, err := domMQOMSystem.system(mqom.PRG, mqom.Field, mqom.M, mqom.N, seed[:pk_seed_len])
+	if err != nil {
+		return nil, nil
+	}
+	S := domMQOMSecret.sample(mqom.Field, mqom.N, seed[pk_seed_len:])
+	return mqom.KeyPair(P, S, P.Eval(S))
+}
+
+func (mqom *MQOM) KeyPair(F *math.MQSystem, S, V []uint8) (*MQDSSSecretKey, *MQDSSPublicKey) {
+	sk := new(MQDSSSecretKey)
+	pk := new(MQDSSPublicKey)
+
+	sk.S = S
+	pk.F = F
+	pk.V = V
+	sk.Pk = pk
+
+	return sk, pk
+}
+
+func (mqom *MQOM) Sign(message []uint8, sk *MQDSSSecretKey) []byte {
+	return mqom.SignWith(message, sk, mqom.Mode)
+}
+
+// SignWith signs with the given mode instead of that of mqom.
+func (mqom *MQOM) SignWith(message []uint8, sk *MQDSSSecretKey, mode SigningMode) []byte {
+	F, E := mqom.Field, mqom.Ext
+	if len(sk.S) != mqom.N {
+		return nil
+	}
+	packed := F.Pack(sk.S)
+	salt_seed := mode.seed(domMQOMSeed, constants.HASH_BYTES+2*constants.LAMBDA/8,
+		packed, mqom.digest(nil, message, sk.Pk))
+	Wipe(packed)
+	if salt_seed == nil {
+		return nil
+	}
+	defer Wipe(salt_seed)
+	salt, seed := salt_seed[:constants.HASH_BYTES], salt_seed[constants.HASH_BYTES:]
+	D := mqom.digest(salt, message, sk.Pk)
+	roots := domMQOMRoots.bytes(mqom.Tau*constants.LAMBDA/8, seed, D)
+	defer Wipe(roots)
+
+	reps := make([]*mqomRep, mqom.Tau)
+	tohash := bytes.Clone(D)
+	dxs := make([]uint8, 0, mqom.Tau*mqom.N)
+	for e := range reps {
+		r := mqom.shareRep(D, e, roots[e*constants.LAMBDA/8:(e+1)*constants.LAMBDA/8])
+		r.dx = make([]uint8, mqom.N)
+		for j := range r.dx {
+			r.dx[j] = F.Sub(sk.S[j], r.x[j])
+		}
+		r.x = sk.S
+		for _, com := range r.coms {
+			tohash = append(tohash, com...)
+		}
+		dxs = append(dxs, r.dx...)
+		reps[e] = r
+	}
+	h1 := domMQOMH1.hash(tohash, F.Pack(dxs))
+
+	gammas := domMQOMGamma.sample(F, mqom.Tau*mqom.M*E.K, h1)
+	dcs := make([]uint8, 0, mqom.Tau*E.K)
+	for e, r := range reps {
+		r.gamma = gammas[e*mqom.M*E.K : (e+1)*mqom.M*E.K]
+		r.dc = E.Sub(mqom.bilinear(sk.Pk, r.gamma, r.a, r.x), r.c)
+		dcs = append(dcs, r.dc...)
+	}
+	h2 := domMQOMH2.hash(h1, F.Pack(dcs))
+
+	epss := domMQOMEps.nonZeroExt(E, mqom.Tau, h2)
+	tohash = bytes.Clone(h2)
+	for e, r := range reps {
+		eps := epss[e*E.K : (e+1)*E.K]
+		t := mqom.target(sk.Pk, r.gamma)
+		r.alpha = mqom.mask(eps, r.x, r.a)
+		tohash = append(tohash, F.Pack(r.alpha)...)
+		for d := 0; d < mqom.D; d++ {
+			alpha, v := mqom.party(sk.Pk, r, eps, t, r.xs[d], r.as[d], r.cs[d], true)
+			tohash = append(tohash, F.Pack(append(alpha, v...))...)
+		}
+	}
+	h3 := domMQOMH3.hash(tohash)
+
+	hidden := mqom.hidden(h3)
+	sig := append(bytes.Clone(salt), h3...)
+	for e, r := range reps {
+		opened := make([]bool, 1<<mqom.D)
+		for i := range opened {
+			opened[i] = i != hidden[e]
+		}
+		for _, s := range r.tree.reveal(opened) {
+			sig = append(sig, s...)
+		}
+		sig = append(sig, r.coms[hidden[e]]...)
+		sig = append(sig, F.Pack(r.dx)...)
+		sig = append(sig, F.Pack(r.dc)...)
+		sig = append(sig, F.Pack(r.alpha)...)
+	}
+	return sig
+}
+
+func (mqom *MQOM) Verify(message []uint8, sig []byte, pk *MQDSSPublicKey) bool {
+	F, E := mqom.Field, mqom.Ext
+	seedLen := constants.LAMBDA / 8
+	lenX := F.PackedLen(mqom.N)
+	lenC := F.PackedLen(E.K)
+	lenA := F.PackedLen(mqom.N * E.K)
+	if len(sig) != mqom.SignatureSize() {
+		return false
+	}
+
+	salt := sig[:constants.HASH_BYTES]
+	h3 := sig[constants.HASH_BYTES : 2*constants.HASH_BYTES]
+	rest := sig[2*constants.HASH_BYTES:]
+	D := mqom.digest(salt, message, pk)
+	hidden := mqom.hidden(h3)
+
+	reps := make([]*mqomRep, mqom.Tau)
+	tohash := bytes.Clone(D)
+	dxs := make([]uint8, 0, mqom.Tau*mqom.N)
+	dcs := make([]uint8, 0, mqom.Tau*E.K)
+	for e := range reps {
+		revealed := make([][]byte, mqom.D)
+		for j := range revealed {
+			revealed[j] = rest[j*seedLen : (j+1)*seedLen]
+		}
+		rest = rest[mqom.D*seedLen:]
+		com := rest[:constants.HASH_BYTES]
+		rest = rest[constants.HASH_BYTES:]
+		dx := F.Unpack(rest[:lenX], mqom.N)
+		dc := F.Unpack(rest[lenX:lenX+lenC], E.K)
+		alpha := F.Unpack(rest[lenX+lenC:lenX+lenC+lenA], mqom.N*E.K)
+		rest = rest[lenX+lenC+lenA:]
+		if dx == nil || dc == nil || alpha == nil {
+			return false
+		}
+
+		r := mqom.openRep(D, e, revealed, hidden[e], dx, dc)
+		if r == nil {
+			return false
+		}
+		r.coms[hidden[e]] = com
+		r.alpha = alpha
+		for _, c := range r.coms {
+			tohash = append(tohash, c...)
+		}
+		dxs = append(dxs, dx...)
+		dcs = append(dcs, dc...)
+		reps[e] = r
+	}
+	h1 := domMQOMH1.hash(tohash, F.Pack(dxs))
+	gammas := domMQOMGamma.sample(F, mqom.Tau*mqom.M*E.K, h1)
+	h2 := domMQOMH2.hash(h1, F.Pack(dcs))
+	epss := domMQOMEps.nonZeroExt(E, mqom.Tau, h2)
+
+	tohash = bytes.Clone(h2)
+	for e, r := range reps {
+		r.gamma = gammas[e*mqom.M*E.K : (e+1)*mqom.M*E.K]
+		eps := epss[e*E.K : (e+1)*E.K]
+		t := mqom.target(pk, r.gamma)
+		tohash = append(tohash, F.Pack(r.alpha)...)
+		for d := 0; d < mqom.D; d++ {
+			// the main party of dimension d without the hidden party is fully
+			// known, the other one follows from the sums alpha and 0
+			first := hidden[e]>>d&1 == 1
+			alpha, v := mqom.party(pk, r, eps, t, r.xs[d], r.as[d], r.cs[d], first)
+			if !first {
+				for j := range alpha {
+					alpha[j] = F.Sub(r.alpha[j], alpha[j])
+				}
+				v = E.Neg(v)
+			}
+			tohash = append(tohash, F.Pack(append(alpha, v...))...)
+		}
+	}
+	h3_prime := domMQOMH3.hash(tohash)
+	return bytes.Equal(h3, h3_prime)
+}
+
+// SignatureSize is the length in bytes of the signatures of mqom.
+func (mqom *MQOM) SignatureSize() int {
+	F, k := mqom.Field, mqom.Ext.K
+	return 2*constants.HASH_BYTES + mqom.Tau*(mqom.D*constants.LAMBDA/8+constants.HASH_BYTES+
+		F.PackedLen(mqom.N)+F.PackedLen(k)+F.PackedLen(mqom.N*k))
+}
+
+// ExpectedSignatureSize is SignatureSize, the signatures having a fixed
+// length.
+func (mqom *MQOM) ExpectedSignatureSize() int {
+	return mqom.SignatureSize()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// mqomRep is the state of one repetition: the sums of the shares over all
+// the parties (x, a, c) and over the first main party of every dimension
+// (xs, as, cs), i.e. the parties whose index has bit d unset.
+type mqomRep struct {
+	tree       *seedTree
+	coms       [][]byte
+	x, a, c    []uint8
+	xs, as, cs [][]uint8
+	dx, dc     []uint8
+	gamma      []uint8
+	alpha      []uint8
+}
+
+// digest binds the salt, the statement and the message.
+func (mqom *MQOM) digest(salt, message []byte, pk *MQDSSPublicKey) []byte {
+	F := mqom.Field
+	statement := domMQOMStatement.hash(F.Pack(pk.F.Coeffs), F.Pack(pk.V))
+	return domMQOMDigest.hash(salt, statement, message)
+}
+
+func (mqom *MQOM) treeSalt(D []byte, e int) []byte {
+	return binary.BigEndian.AppendUint16(bytes.Clone(D), uint16(e))
+}
+
+func (mqom *MQOM) commitLeaf(D []byte, e, i int, seed []byte) []byte {
+	return domMQOMCommit.hash(D, u32(e), u32(i), seed)
+}
+
+// share expands the shares of x, a and c of the party with the given seed.
+func (mqom *MQOM) share(seed []byte) ([]uint8, []uint8, []uint8) {
+	n, k := mqom.N, mqom.Ext.K
+	xac := domMQOMShare.sample(mqom.Field, n+n*k+k, seed)
+	return xac[:n], xac[n : n+n*k], xac[n+n*k:]
+}
+
+func (mqom *MQOM) newRep() *mqomRep {
+	n, k := mqom.N, mqom.Ext.K
+	r := &mqomRep{
+		coms: make([][]byte, 1<<mqom.D),
+		x:    make([]uint8, n), a: make([]uint8, n*k), c: make([]uint8, k),
+		xs: make([][]uint8, mqom.D), as: make([][]uint8, mqom.D), cs: make([][]uint8, mqom.D),
+	}
+	for d := 0; d < mqom.D; d++ {
+		r.xs[d], r.as[d], r.cs[d] = make([]uint8, n), make([]uint8, n*k), make([]uint8, k)
+	}
+	return r
+}
+
+// shareRep expands the shares of every party of repetition e.
+func (mqom *MQOM) shareRep(D []byte, e int, root []byte) *mqomRep {
+	F := mqom.Field
+	r := mqom.newRep()
+	r.tree = newSeedTree(root, mqom.treeSalt(D, e), 1<<mqom.D)
+	for i := 0; i < 1<<mqom.D; i++ {
+		seed := r.tree.leaf(i)
+		r.coms[i] = mqom.commitLeaf(D, e, i, seed)
+		x, a, c := mqom.share(seed)
+		addTo(F, r.x, x)
+		addTo(F, r.a, a)
+		addTo(F, r.c, c)
+		for d := 0; d < mqom.D; d++ {
+			if i>>d&1 == 0 {
+				addTo(F, r.xs[d], x)
+				addTo(F, r.as[d], a)
+				addTo(F, r.cs[d], c)
+			}
+		}
+	}
+	return r
+}
+
+// openRep recomputes the shares of the parties of repetition e but the
+// hidden one, summed for every dimension over the main party that does not
+// contain it.
+func (mqom *MQOM) openRep(D []byte, e int, revealed [][]byte, hidden int, dx, dc []uint8) *mqomRep {
+	F := mqom.Field
+	last := 1<<mqom.D - 1
+	opened := make([]bool, 1<<mqom.D)
+	for i := range opened {
+		opened[i] = i != hidden
+	}
+	seeds := recoverSeeds(revealed, opened, mqom.treeSalt(D, e))
+	if seeds == nil {
+		return nil
+	}
+	r := mqom.newRep()
+	for i, seed := range seeds {
+		if i == hidden {
+			continue
+		}
+		r.coms[i] = mqom.commitLeaf(D, e, i, seed)
+		x, a, c := mqom.share(seed)
+		if i == last {
+			addTo(F, x, dx)
+			addTo(F, c, dc)
+		}
+		for d := 0; d < mqom.D; d++ {
+			if i>>d&1 != hidden>>d&1 {
+				addTo(F, r.xs[d], x)
+				addTo(F, r.as[d], a)
+				addTo(F, r.cs[d], c)
+			}
+		}
+	}
+	return r
+}
+
+// party computes the opening of alpha and the broadcast v of a main party
+// holding shares x, a and c; the first main parties also hold eps <gamma, v>.
+func (mqom *MQOM) party(pk *MQDSSPublicKey, r *mqomRep, eps, t, x, a, c []uint8, first bool) ([]uint8, []uint8) {
+	E := mqom.Ext
+	v := E.Sub(mqom.bilinear(pk, r.gamma, r.alpha, x), c)
+	if first {
+		v = E.Sub(v, E.Mul(eps, t))
+	}
+	return mqom.mask(eps, x, a), v
+}
+
+// mask returns eps x + a.
+func (mqom *MQOM) mask(eps, x, a []uint8) []uint8 {
+	E := mqom.Ext
+	k := E.K
+	res := make([]uint8, len(a))
+	for j, xj := range x {
+		copy(res[j*k:(j+1)*k], E.Add(E.MulBase(eps, xj), a[j*k:(j+1)*k]))
+	}
+	return res
+}
+
+// target returns <gamma, v>.
+func (mqom *MQOM) target(pk *MQDSSPublicKey, gamma []uint8) []uint8 {
+	E := mqom.Ext
+	k := E.K
+	t := make([]uint8, k)
+	for i, vi := range pk.V {
+		t = E.Add(t, E.MulBase(gamma[i*k:(i+1)*k], vi))
+	}
+	return t
+}
+
+// bilinear returns sum_i gamma_i u^T U_i x for u over the extension and x
+// over the base field, one coordinate of u at a time.
+func (mqom *MQOM) bilinear(pk *MQDSSPublicKey, gamma, u, x []uint8) []uint8 {
+	E := mqom.Ext
+	k := E.K
+	B := make([]uint8, mqom.M*k)
+	ul := make([]uint8, mqom.N)
+	for l := 0; l < k; l++ {
+		for j := range ul {
+			ul[j] = u[j*k+l]
+		}
+		for i, b := range pk.F.Bilinear(ul, x) {
+			B[i*k+l] = b
+		}
+	}
+	res := make([]uint8, k)
+	for i := 0; i < mqom.M; i++ {
+		res = E.Add(res, E.Mul(gamma[i*k:(i+1)*k], B[i*k:(i+1)*k]))
+	}
+	return res
+}
+
+// hidden expands the index of the hidden party of every repetition.
+func (mqom *MQOM) hidden(h3 []byte) []int {
+	b := domMQOMHidden.bytes(2*mqom.Tau, h3)
+	res := make([]int, mqom.Tau)
+	for e := range res {
+		res[e] = int(binary.BigEndian.Uint16(b[2*e:])) & (1<<mqom.D - 1)
+	}
+	return res
+}
+
+func addTo(F math.Field, dst, src []uint8) {
+	for j := range dst {
+		dst[j] = F.Add(dst[j], src[j])
+	}
+}
@@ -0,0 +1,137 @@
+package crypto
+
+import (
+	constants "mqat/const"
+	"mqat/math"
+)
+
+// ParameterSet bundles the choices defining an MQAT instance; seed and
+// salt lengths are those of the const package.
+type ParameterSet struct {
+	Name   string
+	Field  math.Field
+	M, N   int
+	Rounds int
+	// Degree is the extension degree of the MQDSS challenges and masks, 1 to
+	// keep them in Field.
+	Degree int
+	// Compact selects the seed tree and Merkle tree MQDSS format.
+	Compact bool
+	// PRG expands the public matrices, SHAKE128 if nil.
+	PRG PRG
+	// LogParties selects the MQOM proof over 2^LogParties parties instead
+	// of MQDSS, Rounds being its repetitions; 0 keeps MQDSS.
+	LogParties int
+	// NISTUOV issues with the NIST UOV keys in this format; 0 keeps the
+	// plain UOV.
+	NISTUOV UOVVariant
+	// MAYOOil and MAYOWhip issue with MAYO, oil space of dimension MAYOOil
+	// whipped MAYOWhip times; 0 keeps UOV.
+	MAYOOil, MAYOWhip int
+}
+
+// The GF(256) sets expand their public matrices with AES-128-CTR, which
+// runs on the AES instructions where available. They used SHAKE128 before
+// under the same names, whose keys and tokens are not valid under these
+// sets; options passed to New override those of the set, so
+// p.New(WithPRG(SHAKE128)) still builds the old instance.
+var (
+	MQAT_GF256 = ParameterSet{
+		Name: "MQAT-GF256", Field: math.GF256, M: constants.M, N: constants.N,
+		Rounds: constants.MQDSS_ROUNDS, Degree: 1, PRG: AES128CTR,
+	}
+	// Not a size reduction: challenges in GF(256^2) save 17 rounds
+	// (MQDSSRounds(256*256, 128)), the 5-pass soundness error staying above
+	// 1/2, but double the size of the t1/e1 responses, so tokens grow from
+	// 61092 to 82523 bytes. Kept to compare with the other sets.
+	MQAT_GF256_EXT2 = ParameterSet{
+		Name: "MQAT-GF256-EXT2", Field: math.GF256, M: constants.M, N: constants.N,
+		Rounds: 139, Degree: 2, PRG: AES128CTR,
+	}
+	MQAT_GF256_COMPACT = ParameterSet{
+		Name: "MQAT-GF256-COMPACT", Field: math.GF256, M: constants.M, N: constants.N,
+		Rounds: constants.MQDSS_ROUNDS, Degree: 1, Compact: true, PRG: AES128CTR,
+	}
+	// 256 parties and challenges in GF(256^2) need 25 repetitions
+	// (MQOMRepetitions(256*256, 8, 128)).
+	MQAT_GF256_MQOM = ParameterSet{
+		Name: "MQAT-GF256-MQOM", Field: math.GF256, M: constants.M, N: constants.N,
+		Rounds: 25, Degree: 2, LogParties: 8, PRG: AES128CTR,
+	}
+	// The issuer signs with uov-Is, compressed public key, so the tokens are
+	// over GF(16) and need 206 rounds (MQDSSRounds(16, 128)): the 44
+	// equations of uov-Ip in the 156 token variables fall below 128 bits.
+	MQAT_GF16_NISTUOV = ParameterSet{
+		Name: "MQAT-GF16-NISTUOV", Field: math.GF16, M: 64, N: 160,
+		Rounds: 206, Degree: 1, PRG: AES128CTR, NISTUOV: UOVPkc,
+	}
+	// The issuer signs with MAYO. Forgeries solve the 48 equations of the
+	// whipped map in 192 variables, so m grows from 45 to 48, and n-o must
+	// stay at least m against the key recovery of the oil space; the issuer
+	// public key is a seed and P3 (6544 bytes against 284760 for UOV) but
+	// the tokens prove a preimage over 240 variables instead of 157.
+	MQAT_GF256_MAYO = ParameterSet{
+		Name: "MQAT-GF256-MAYO", Field: math.GF256, M: 48, N: 64,
+		Rounds: constants.MQDSS_ROUNDS, Degree: 1, PRG: AES128CTR, MAYOOil: 16, MAYOWhip: 3,
+	}
+)
+
+// ParameterSetByName returns the parameter set named name, e.g. to load a
+// key file.
+func ParameterSetByName(name string) (ParameterSet, bool) {
+	for _, p := range []ParameterSet{MQAT_GF256, MQAT_GF256_EXT2, MQAT_GF256_COMPACT, MQAT_GF256_MQOM, MQAT_GF16_NISTUOV, MQAT_GF256_MAYO} {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return ParameterSet{}, false
+}
+
+func (p ParameterSet) New(opts ...Option) *MQAT {
+	mqat := NewMQATOver(
+		p.Field,
+		p.N, p.M,
+		constants.SALT_LEN,
+		constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN,
+		p.Rounds, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN,
+		append(p.options(), opts...)...,
+	)
+	if mqat != nil {
+		mqat.name = p.Name
+	}
+	return mqat
+}
+
+func (p ParameterSet) Security() SecurityEstimate {
+	return estimateMQATWith(p.Field.Order(), p.M, p.N, p.Rounds, newOptions(p.options()))
+}
+
+// TokenSize is the length in bytes of the tokens of p, or their maximum
+// length in the compact format.
+func (p ParameterSet) TokenSize() int {
+	return p.New(Insecure()).TokenSize()
+}
+
+// ExpectedTokenSize is the mean length in bytes of the tokens of p.
+func (p ParameterSet) ExpectedTokenSize() int {
+	return p.New(Insecure()).ExpectedTokenSize()
+}
+
+// options returns the constructor options selecting the choices of p.
+func (p ParameterSet) options() []Option {
+	opts := []Option{WithExtension(p.Degree), WithPRG(p.PRG)}
+	if p.Compact {
+		opts = append(opts, Compact())
+	}
+	if p.LogParties > 0 {
+		opts = append(opts, WithMQOM(p.LogParties))
+	}
+	if p.NISTUOV != 0 {
+		opts = append(opts, WithNISTUOV(p.NISTUOV))
+	}
+	if p.MAYOWhip > 0 {
+		opts = append(opts, WithMAYO(p.MAYOOil, p.MAYOWhip))
+	}
+	return opts
+}
@@ -0,0 +1,105 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var ErrSeedLength = errors.New("crypto: seed length not supported by the PRG")
+
+// PRG expands a seed into a stream, for the public matrices of UOV, MQDSS,
+// MQOM and MQAT. The domain-separated inputs are absorbed as they are by
+// the SHAKE backends and hashed into a key of SeedLen bytes for the others.
+type PRG interface {
+	Name() string
+	// SeedLen is the length of the seeds accepted by Expand, 0 for any.
+	SeedLen() int
+	Expand(seed []byte) io.Reader
+}
+
+var (
+	SHAKE128  PRG = shakePRG(128)
+	SHAKE256  PRG = shakePRG(256)
+	AES128CTR PRG = aesCTR{}
+)
+
+// PRGByName returns the PRG named name, or nil.
+func PRGByName(name string) PRG {
+	for _, prg := range []PRG{SHAKE128, SHAKE256, AES128CTR} {
+		if prg.Name() == name {
+			return prg
+		}
+	}
+	return nil
+}
+
+type shakePRG int
+
+func (s shakePRG) Name() string {
+	if s == 128 {
+		return "SHAKE128"
+	}
+	return "SHAKE256"
+}
+
+func (shakePRG) SeedLen() int {
+	return 0
+}
+
+func (s shakePRG) Expand(seed []byte) io.Reader {
+	xof := sha3.NewShake256()
+	if s == 128 {
+		xof = sha3.NewShake128()
+	}
+	xof.Write(seed)
+	return xof
+}
+
+// aesCTR is AES-128 in counter mode from a zero IV, keyed by the seed, as in
+// the UOV specification.
+type aesCTR struct{}
+
+func (aesCTR) Name() string {
+	return "AES-128-CTR"
+}
+
+func (aesCTR) SeedLen() int {
+	return 16
+}
+
+func (aesCTR) Expand(seed []byte) io.Reader {
+	if len(seed) != 16 {
+		return errReader{ErrSeedLength}
+	}
+	block, err := aes.NewCipher(seed)
+	if err != nil {
+		return errReader{err}
+	}
+	return &ctrReader{cipher.NewCTR(block, make([]byte, aes.BlockSize))}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+type ctrReader struct {
+	stream cipher.Stream
+}
+
+func (r *ctrReader) Read(p []byte) (int, error) {
+	clear(p)
+	r.stream.XORKeyStream(p, p)
+	return len(p), nil
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
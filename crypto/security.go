@@ -0,0 +1,426 @@
+package crypto
+
+import (
+	"math"
+	constants "mqat/const"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Bit-security estimates from the standard cost formulas. They are meant to
+// rule out parameter choices with trivial security, not to replace a proper
+// analysis: every attack is costed in field multiplications and lower-order
+// improvements are ignored.
+
+// SecurityEstimate is the cost, in bits, of each attack considered. Attacks
+// that do not apply are +Inf.
+type SecurityEstimate struct {
+	Direct         float64 // forgery by solving the public system
+	Reconciliation float64 // UOV oil space recovery, Ding et al.
+	Intersection   float64 // UOV oil space recovery, Beullens
+	KipnisShamir   float64 // UOV oil space recovery, Kipnis et al.
+	Soundness      float64 // Fiat-Shamir attack on the proof of knowledge
+}
+
+func (e SecurityEstimate) Bits() float64 {
+	return math.Min(math.Min(e.Direct, e.Reconciliation),
+		math.Min(math.Min(e.Intersection, e.KipnisShamir), e.Soundness))
+}
+
+func noAttack() SecurityEstimate {
+	inf := math.Inf(1)
+	return SecurityEstimate{inf, inf, inf, inf, inf}
+}
+
+// Option configures the security check done by the constructors.
+type Option func(*options)
+
+type options struct {
+	target   int
+	insecure bool
+	degree   int
+	compact  bool
+	// interactive: MQDSS runs the 5-pass protocol, without Fiat-Shamir
+	interactive bool
+	mqom        int
+	prg         PRG
+	mode        SigningMode
+	nist        UOVVariant
+	// mayoO, mayoK: oil dimension and whipping of the MAYO issuer, 0 for UOV
+	mayoO, mayoK int
+	// set records the scheme-specific options given, which the constructors
+	// check against those they take
+	set optionSet
+}
+
+type optionSet uint
+
+const (
+	optExtension optionSet = 1 << iota
+	optCompact
+	optInteractive
+	optMQOM
+	optPRG
+	optSigningMode
+	optNISTUOV
+	optMAYO
+)
+
+var optionNames = []string{"WithExtension", "Compact", "Interactive", "WithMQOM", "WithPRG", "WithSigningMode", "WithNISTUOV", "WithMAYO"}
+
+func newOptions(opts []Option) options {
+	o := options{target: constants.LAMBDA, degree: 1, prg: SHAKE128}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Insecure disables the security check, for tests and toy parameters only.
+func Insecure() Option {
+	return func(o *options) { o.insecure = true }
+}
+
+// WithSecurityTarget sets the minimum estimated bit-security accepted by the
+// constructors, LAMBDA by default.
+func WithSecurityTarget(bits int) Option {
+	return func(o *options) { o.target = bits }
+}
+
+// WithExtension draws the MQDSS challenges and masks from the extension of
+// degree k of the field, which lowers the soundness error per round.
+func WithExtension(k int) Option {
+	return func(o *options) { o.degree, o.set = k, o.set|optExtension }
+}
+
+// Compact selects the MQDSS signature format where the per-round randomness
+// comes from a seed tree and the commitments from a Merkle tree. Rounds
+// opened with b = 1 still reveal r1, t1 and e1, so signatures cannot go
+// below about half the size of the original format.
+func Compact() Option {
+	return func(o *options) { o.compact, o.set = true, o.set|optCompact }
+}
+
+// Interactive builds MQDSS for the interactive 5-pass identification
+// protocol only, whose soundness is not exposed to offline grinding.
+func Interactive() Option {
+	return func(o *options) { o.interactive, o.set = true, o.set|optInteractive }
+}
+
+// WithMQOM proves MQAT tokens with the MPC-in-the-head proof over 2^d
+// parties instead of MQDSS; the rounds are then its repetitions.
+func WithMQOM(d int) Option {
+	return func(o *options) { o.mqom, o.set = d, o.set|optMQOM }
+}
+
+// WithPRG expands the public matrices with prg, SHAKE128 by default.
+func WithPRG(prg PRG) Option {
+	return func(o *options) {
+		if prg != nil {
+			o.prg = prg
+		}
+		o.set |= optPRG
+	}
+}
+
+// EstimateUOV estimates UOV with m equations in n variables over GF(q).
+func EstimateUOV(q, m, n int) SecurityEstimate {
+	e := noAttack()
+	lq := math.Log2(float64(q))
+	v := n - m
+
+	e.Direct = DirectAttackBits(q, m, n)
+
+	if v <= m {
+		e.KipnisShamir = 4 * math.Log2(float64(n))
+		e.Reconciliation = DirectAttackBits(q, m, v)
+	} else {
+		e.KipnisShamir = float64(v-m)*lq + 4*math.Log2(float64(n))
+		e.Reconciliation = float64(v-m)*lq + DirectAttackBits(q, m, m)
+	}
+
+	// intersection attack with k = 2: 3m-2 equations in 2n-3m variables,
+	// and only succeeds with probability q^-(n-3m+1) when n >= 3m
+	if n < 3*m {
+		e.Intersection = DirectAttackBits(q, 3*m-2, 2*n-3*m)
+	} else {
+		e.Intersection = float64(n-3*m+1)*lq + DirectAttackBits(q, 3*m-2, 3*m-3)
+	}
+	return e
+}
+
+// EstimateMAYO estimates the whipped UOV of MAYO: m equations in n variables
+// with an oil space of dimension o < m, whipped k times. Forgeries solve the
+// m equations of the whipped map in kn variables; the key recovery attacks
+// are those on UOV with the small oil space.
+func EstimateMAYO(q, m, n, o, k int) SecurityEstimate {
+	e := noAttack()
+	lq := math.Log2(float64(q))
+	v := n - o
+
+	e.Direct = DirectAttackBits(q, m, k*n)
+
+	if v <= o {
+		e.KipnisShamir = 4 * math.Log2(float64(n))
+		e.Reconciliation = DirectAttackBits(q, m, v)
+	} else {
+		e.KipnisShamir = float64(v-o)*lq + 4*math.Log2(float64(n))
+		e.Reconciliation = float64(v-o)*lq + DirectAttackBits(q, m, o)
+	}
+	// the oil space meets every subspace of dimension n-o+1, so solving
+	// P(x) = 0 there, in n-o variables up to scaling, finds an oil vector:
+	// this takes n-o >= m to be hard
+	e.Reconciliation = math.Min(e.Reconciliation, DirectAttackBits(q, m, v))
+
+	if n < 3*o {
+		e.Intersection = DirectAttackBits(q, 3*m-2, 2*n-3*o)
+	} else {
+		e.Intersection = float64(n-3*o+1)*lq + DirectAttackBits(q, 3*m-2, 3*o-3)
+	}
+	return e
+}
+
+// EstimateMQDSS estimates MQDSS with m equations in n variables over GF(q)
+// and the given number of rounds.
+func EstimateMQDSS(q, m, n, rounds int) SecurityEstimate {
+	return EstimateMQDSSExt(q, 1, m, n, rounds)
+}
+
+// EstimateMQDSSExt estimates MQDSS with challenges in GF(q^k).
+func EstimateMQDSSExt(q, k, m, n, rounds int) SecurityEstimate {
+	e := noAttack()
+	e.Direct = DirectAttackBits(q, m, n)
+	e.Soundness = FiatShamirBits(pow(q, k)-1, rounds)
+	return e
+}
+
+// EstimateMQOM estimates the MPC-in-the-head proof over 2^d parties with
+// tau repetitions and challenges in GF(q^k).
+func EstimateMQOM(q, k, m, n, tau, d int) SecurityEstimate {
+	e := noAttack()
+	e.Direct = DirectAttackBits(q, m, n)
+	e.Soundness = MQOMBits(pow(q, k), d, tau)
+	return e
+}
+
+// EstimateMQAT estimates MQAT, whose issuer signs with UOV over m equations
+// in n variables and whose tokens are MQDSS proofs for m equations in n+m
+// variables.
+func EstimateMQAT(q, m, n, rounds int) SecurityEstimate {
+	return EstimateMQATExt(q, 1, m, n, rounds)
+}
+
+// EstimateMQATExt estimates MQAT with MQDSS challenges in GF(q^k).
+func EstimateMQATExt(q, k, m, n, rounds int) SecurityEstimate {
+	return estimateMQAT(EstimateMQDSSExt(q, k, m, n+m, rounds), EstimateUOV(q, m, n))
+}
+
+// EstimateMQATMQOM estimates MQAT with tokens proven by the MPC-in-the-head
+// proof over 2^d parties with tau repetitions.
+func EstimateMQATMQOM(q, k, m, n, tau, d int) SecurityEstimate {
+	return estimateMQAT(EstimateMQOM(q, k, m, n+m, tau, d), EstimateUOV(q, m, n))
+}
+
+// estimateMQATWith estimates MQAT with the proof and issuer selected by o.
+func estimateMQATWith(q, m, n, rounds int, o options) SecurityEstimate {
+	u, issued := EstimateUOV(q, m, n), n
+	if o.mayoK > 0 {
+		u, issued = EstimateMAYO(q, m, n, o.mayoO, o.mayoK), o.mayoK*n
+	}
+	if o.mqom > 0 {
+		return estimateMQAT(EstimateMQOM(q, o.degree, m, m+issued, rounds, o.mqom), u)
+	}
+	return estimateMQAT(EstimateMQDSSExt(q, o.degree, m, m+issued, rounds), u)
+}
+
+// estimateMQAT combines the estimates of the proof d and of the issuer u.
+func estimateMQAT(d, u SecurityEstimate) SecurityEstimate {
+	u.Direct = math.Min(u.Direct, d.Direct)
+	u.Soundness = d.Soundness
+	return u
+}
+
+// DirectAttackBits is the cost of finding a solution of m random quadratic
+// equations in n variables over GF(q). Underdetermined systems are first
+// reduced following Thomae and Wolf, then the hybrid approach guesses k
+// variables and solves the rest with XL/Wiedemann at the degree of
+// regularity of a semi-regular system.
+func DirectAttackBits(q, m, n int) float64 {
+	if m <= 0 || n <= 0 {
+		return 0
+	}
+	if n >= m*(m+1) {
+		// Kipnis, Patarin and Goubin: polynomial time
+		return 3 * math.Log2(float64(n))
+	}
+	if n > m {
+		m = m - n/m + 1
+		n = m
+	}
+
+	lq := math.Log2(float64(q))
+	best := math.Inf(1)
+	for k := 0; k < n; k++ {
+		nk := n - k
+		D := degreeOfRegularity(m, nk)
+		if D < 0 {
+			continue
+		}
+		cost := float64(k)*lq + math.Log2(3) +
+			2*log2Binomial(nk+D, D) + log2Binomial(nk+2, 2)
+		best = math.Min(best, cost)
+	}
+	return math.Min(best, float64(n)*lq)
+}
+
+// SoundnessError is the probability that a cheater passes one round of the
+// 5-pass identification scheme with the given number of challenges alpha: it
+// either guesses alpha, or else guesses the challenge bit.
+func SoundnessError(challenges int) float64 {
+	c := float64(challenges)
+	return (c + 1) / (2 * c)
+}
+
+// FiatShamirBits is the cost of the Kales-Zaverucha attack on the
+// Fiat-Shamir transform of the 5-pass identification scheme with the given
+// number of challenges alpha: the cheater grinds the first challenges until
+// at least r1 alphas are guessed, then grinds the remaining bits.
+func FiatShamirBits(challenges, rounds int) float64 {
+	return grindingBits(1/float64(challenges), 1, rounds)
+}
+
+// MQOMBits is the cost of the same attack on the MPC-in-the-head proof over
+// 2^d parties with the given order of the challenge field: a repetition is
+// cheated on with probability about 2/challenges for gamma or eps, and
+// otherwise by guessing the hidden party.
+func MQOMBits(challenges, d, tau int) float64 {
+	return grindingBits(2/float64(challenges), float64(d), tau)
+}
+
+// MQOMRepetitions derives the number of repetitions for which MQOMBits
+// reaches lambda bits.
+func MQOMRepetitions(challenges, d, lambda int) int {
+	for tau := 1; ; tau++ {
+		if MQOMBits(challenges, d, tau) >= float64(lambda) {
+			return tau
+		}
+	}
+}
+
+// InteractiveBits is the soundness of the interactive 5-pass identification
+// scheme run for the given number of parallel rounds, in bits.
+func InteractiveBits(challenges, rounds int) float64 {
+	return -float64(rounds) * math.Log2(SoundnessError(challenges))
+}
+
+// InteractiveRounds derives the number of rounds for which the interactive
+// protocol reaches lambda bits.
+func InteractiveRounds(challenges, lambda int) int {
+	return int(math.Ceil(float64(lambda) / -math.Log2(SoundnessError(challenges))))
+}
+
+// MQDSSRounds derives the number of rounds for which the Fiat-Shamir
+// transform reaches lambda bits with challenges alpha drawn from the nonzero
+// elements of GF(q), q being the order of the extension for MQDSS with
+// extension-field challenges.
+func MQDSSRounds(q, lambda int) int {
+	// every round costs at least one bit to the cheater
+	for r := lambda; ; r++ {
+		if FiatShamirBits(q-1, r) >= float64(lambda) {
+			return r
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// accepts reports whether the scheme-specific options given are all in
+// allowed; Insecure and WithSecurityTarget apply to every constructor.
+func (o options) accepts(scheme string, allowed optionSet) bool {
+	for i, name := range optionNames {
+		if o.set&^allowed&(1<<i) != 0 {
+			logrus.Errorf("%s does not take the %s option", scheme, name)
+			return false
+		}
+	}
+	return true
+}
+
+// secure reports whether e meets the target set in o, logging the attack
+// that falls short otherwise. Estimates are not rounded: 127.5 bits do not
+// meet a target of 128.
+func secure(scheme string, e SecurityEstimate, o options) bool {
+	if o.insecure || e.Bits() >= float64(o.target) {
+		return true
+	}
+	logrus.Errorf("%s parameters only reach %.1f bits of security (%+v), below the target of %d bits",
+		scheme, e.Bits(), e, o.target)
+	return false
+}
+
+// degreeOfRegularity returns the index of the first non-positive
+// coefficient of (1 - z^2)^m / (1 - z)^n, or -1 if there is none up to n+1.
+func degreeOfRegularity(m, n int) int {
+	// series of (1 - z^2)^m, truncated
+	maxD := n + 2
+	num := make([]float64, maxD+1)
+	for i := 0; 2*i <= maxD && i <= m; i++ {
+		c := math.Exp2(log2Binomial(m, i))
+		if i%2 == 1 {
+			c = -c
+		}
+		num[2*i] = c
+	}
+	// dividing by (1 - z) n times is taking prefix sums n times
+	for t := 0; t < n; t++ {
+		for d := 1; d <= maxD; d++ {
+			num[d] += num[d-1]
+		}
+	}
+	for d := 0; d <= maxD; d++ {
+		if num[d] <= 0 {
+			return d
+		}
+	}
+	return -1
+}
+
+// grindingBits is the cost of passing rounds repetitions of a proof whose
+// first challenge is cheated on with probability p and whose second costs
+// 2^bits guesses otherwise.
+func grindingBits(p, bits float64, rounds int) float64 {
+	best := math.Inf(1)
+	for r1 := 0; r1 <= rounds; r1++ {
+		tail := 0.0
+		for i := r1; i <= rounds; i++ {
+			tail += math.Exp(log2Binomial(rounds, i)*math.Ln2 +
+				float64(i)*math.Log(p) + float64(rounds-i)*math.Log1p(-p))
+		}
+		if tail <= 0 {
+			continue
+		}
+		cost := math.Log2(1/tail + math.Exp2(bits*float64(rounds-r1)))
+		best = math.Min(best, cost)
+	}
+	return best
+}
+
+func pow(q, k int) int {
+	res := 1
+	for i := 0; i < k; i++ {
+		res *= q
+	}
+	return res
+}
+
+func log2Binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return math.Inf(-1)
+	}
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return (a - b - c) / math.Ln2
+}
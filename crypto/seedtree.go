@@ -0,0 +1,133 @@
+package crypto
+
+import "math"
+
+// GGM seed tree: every node seed is expanded into the seeds of its two
+// children, so any subset of the leaves can be revealed as the few nodes
+// covering exactly that subset. Trees are kept in heap layout, node i having
+// children 2i+1 and 2i+2, with the leaves padded to a power of two.
+
+type seedTree struct {
+	nodes  [][]byte
+	leaves int
+}
+
+func newSeedTree(root, salt []byte, leaves int) *seedTree {
+	d := treeDepth(leaves)
+	t := &seedTree{nodes: make([][]byte, (2<<d)-1), leaves: leaves}
+	t.nodes[0] = root
+	for i := 0; i < (1<<d)-1; i++ {
+		t.nodes[2*i+1], t.nodes[2*i+2] = expandSeed(t.nodes[i], salt, i)
+	}
+	return t
+}
+
+func (t *seedTree) leaf(i int) []byte {
+	return t.nodes[(1<<treeDepth(t.leaves))-1+i]
+}
+
+// reveal returns the seeds of the nodes covering the opened leaves.
+func (t *seedTree) reveal(opened []bool) [][]byte {
+	res := make([][]byte, 0)
+	for _, i := range coveringNodes(opened) {
+		res = append(res, t.nodes[i])
+	}
+	return res
+}
+
+// recoverSeeds expands the revealed seeds into the seeds of the opened
+// leaves, leaving the others nil. It returns nil if the number of seeds does
+// not match the opened leaves.
+func recoverSeeds(revealed [][]byte, opened []bool, salt []byte) [][]byte {
+	d := treeDepth(len(opened))
+	cover := coveringNodes(opened)
+	if len(cover) != len(revealed) {
+		return nil
+	}
+	nodes := make([][]byte, (2<<d)-1)
+	for j, i := range cover {
+		nodes[i] = revealed[j]
+	}
+	for i := 0; i < (1<<d)-1; i++ {
+		if nodes[i] != nil {
+			nodes[2*i+1], nodes[2*i+2] = expandSeed(nodes[i], salt, i)
+		}
+	}
+	res := make([][]byte, len(opened))
+	for i, o := range opened {
+		if o {
+			res[i] = nodes[(1<<d)-1+i]
+		}
+	}
+	return res
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+func treeDepth(leaves int) int {
+	d := 0
+	for 1<<d < leaves {
+		d++
+	}
+	return d
+}
+
+func expandSeed(seed, salt []byte, i int) ([]byte, []byte) {
+	out := domSeedTree.bytes(2*len(seed), salt, seed, u32(i))
+	return out[:len(seed)], out[len(seed):]
+}
+
+// expectedCover is the mean number of nodes covering the opened leaves when
+// each of the leaves is opened with probability 1/2. A node with r real
+// leaves below it is full and not empty with probability 2^-r if r > 0, and
+// covers them if its sibling is not full.
+func expectedCover(leaves int) float64 {
+	d := treeDepth(leaves)
+	real := make([]int, (2<<d)-1)
+	for i := 0; i < leaves; i++ {
+		real[(1<<d)-1+i] = 1
+	}
+	for i := (1 << d) - 2; i >= 0; i-- {
+		real[i] = real[2*i+1] + real[2*i+2]
+	}
+	// padding counts as opened
+	full := func(i int) float64 { return math.Pow(2, -float64(real[i])) }
+	res := 0.0
+	for i, r := range real {
+		if r == 0 {
+			continue
+		}
+		if i == 0 {
+			res += full(i)
+		} else {
+			res += full(i) * (1 - full(i-1+2*(i%2)))
+		}
+	}
+	return res
+}
+
+// coveringNodes lists, in increasing order, the highest nodes whose leaves
+// are all opened or padding, skipping subtrees of padding only.
+func coveringNodes(opened []bool) []int {
+	d := treeDepth(len(opened))
+	// full[i]: no unopened leaf below i, some[i]: some opened leaf below i
+	full := make([]bool, (2<<d)-1)
+	some := make([]bool, (2<<d)-1)
+	for i := 0; i < 1<<d; i++ {
+		full[(1<<d)-1+i] = i >= len(opened) || opened[i]
+		some[(1<<d)-1+i] = i < len(opened) && opened[i]
+	}
+	for i := (1 << d) - 2; i >= 0; i-- {
+		full[i] = full[2*i+1] && full[2*i+2]
+		some[i] = some[2*i+1] || some[2*i+2]
+	}
+	res := make([]int, 0)
+	for i := range full {
+		if full[i] && some[i] && (i == 0 || !full[(i-1)/2]) {
+			res = append(res, i)
+		}
+	}
+	return res
+}
@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"crypto/rand"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SigningMode selects where the per-signature randomness of MQDSS and MQOM,
+// which masks the witness, comes from.
+type SigningMode int
+
+const (
+	// Hedged derives it from the witness, the message and fresh randomness:
+	// a weak or failing RNG degrades it to Deterministic instead of leaking
+	// the witness.
+	Hedged SigningMode = iota
+	// Deterministic derives it from the witness and the message only, so
+	// that signing twice gives the same signature.
+	Deterministic
+	// Randomized takes it from crypto/rand only.
+	Randomized
+)
+
+func (mode SigningMode) String() string {
+	switch mode {
+	case Hedged:
+		return "hedged"
+	case Deterministic:
+		return "deterministic"
+	case Randomized:
+		return "randomized"
+	}
+	return "unknown"
+}
+
+// WithSigningMode sets the signing mode of MQDSS, MQOM and of the tokens of
+// MQAT, Hedged by default.
+func WithSigningMode(mode SigningMode) Option {
+	return func(o *options) { o.mode, o.set = mode, o.set|optSigningMode }
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// seed returns n bytes of signing randomness for the witness and the message
+// binding, or nil if mode is unknown or needs an RNG that failed.
+func (mode SigningMode) seed(d domain, n int, witness, message []byte) []byte {
+	if mode != Hedged && mode != Deterministic && mode != Randomized {
+		return nil
+	}
+	var fresh []byte
+	if mode != Deterministic {
+		fresh = make([]byte, n)
+		if _, err := rand.Read(fresh); err != nil {
+			if mode == Randomized {
+				return nil
+			}
+			logrus.Warn("Could not sample fresh signing randomness, signing deterministically")
+			fresh = nil
+		}
+	}
+	if mode == Randomized {
+		return fresh
+	}
+	return d.bytes(n, witness, message, fresh)
+}
@@ -0,0 +1,133 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Transcript records the intermediate values of one MQDSS signature, as
+// computed by the signer or reconstructed by the verifier, for debugging and
+// test vectors. Values a party does not know, such as the commitments a
+// compact signature hides in the Merkle tree, are left nil.
+type Transcript struct {
+	C, D   []byte
+	Sigma0 []byte // as signed, or as received by the verifier
+	Alphas []uint8
+	H1     []byte // compact format only, as signed or received
+	Rounds []TranscriptRound
+	// Sigma0Prime and H1Prime are recomputed from the commitments and the
+	// responses; a verifier accepts iff they match Sigma0 and H1.
+	Sigma0Prime []byte
+	H1Prime     []byte
+}
+
+// TranscriptRound is the challenge bit and the commitments of one round. The
+// verifier reconstructs the commitment the bit opens and receives the other.
+type TranscriptRound struct {
+	Bit    byte
+	C0, C1 []byte
+}
+
+// SignTraced signs like Sign and records the transcript in tr.
+func (mqdss *MQDSS) SignTraced(message []uint8, sk *MQDSSSecretKey, tr *Transcript) []byte {
+	return mqdss.sign(message, sk, mqdss.Mode, tr)
+}
+
+// VerifyTraced verifies like Verify and records the transcript in tr, up to
+// the first malformed field of sig.
+func (mqdss *MQDSS) VerifyTraced(message []uint8, sig []byte, pk *MQDSSPublicKey, tr *Transcript) bool {
+	return mqdss.verify(message, sig, pk, tr)
+}
+
+// DiffTranscripts lists the values on which a signer and a verifier
+// transcript of the same signature disagree, in protocol order, the first
+// being the likely cause of the others. Values missing from either side are
+// skipped.
+func DiffTranscripts(signer, verifier *Transcript) []string {
+	var diff []string
+	cmp := func(name string, a, b []byte) {
+		if a != nil && b != nil && !bytes.Equal(a, b) {
+			diff = append(diff, fmt.Sprintf("%s: %x != %x", name, a, b))
+		}
+	}
+	cmp("C", signer.C, verifier.C)
+	cmp("D", signer.D, verifier.D)
+	cmp("sigma0", signer.Sigma0, verifier.Sigma0)
+	cmp("alphas", signer.Alphas, verifier.Alphas)
+	cmp("h1", signer.H1, verifier.H1)
+	if len(signer.Rounds) != len(verifier.Rounds) {
+		diff = append(diff, fmt.Sprintf("rounds: %d != %d", len(signer.Rounds), len(verifier.Rounds)))
+	}
+	for i := 0; i < len(signer.Rounds) && i < len(verifier.Rounds); i++ {
+		s, v := signer.Rounds[i], verifier.Rounds[i]
+		cmp(fmt.Sprintf("round %d: bit", i), []byte{s.Bit}, []byte{v.Bit})
+		cmp(fmt.Sprintf("round %d: c0", i), s.C0, v.C0)
+		cmp(fmt.Sprintf("round %d: c1", i), s.C1, v.C1)
+	}
+	cmp("recomputed sigma0", signer.Sigma0Prime, verifier.Sigma0Prime)
+	cmp("recomputed h1", signer.H1Prime, verifier.H1Prime)
+	return diff
+}
+
+// String dumps the transcript in hexadecimal, one value per line.
+func (tr *Transcript) String() string {
+	var b strings.Builder
+	line := func(name string, v []byte) {
+		if v != nil {
+			fmt.Fprintf(&b, "%s = %s\n", name, hex.EncodeToString(v))
+		}
+	}
+	line("C", tr.C)
+	line("D", tr.D)
+	line("sigma0", tr.Sigma0)
+	line("alphas", tr.Alphas)
+	line("h1", tr.H1)
+	for i, r := range tr.Rounds {
+		fmt.Fprintf(&b, "round %d: b = %d\n", i, r.Bit)
+		line("\tc0", r.C0)
+		line("\tc1", r.C1)
+	}
+	line("sigma0'", tr.Sigma0Prime)
+	line("h1'", tr.H1Prime)
+	return b.String()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// The recording methods do nothing on a nil transcript, so that the schemes
+// call them unconditionally.
+
+func (tr *Transcript) start(C, D, sigma0 []byte, rounds int) {
+	if tr == nil {
+		return
+	}
+	*tr = Transcript{C: bytes.Clone(C), D: bytes.Clone(D), Sigma0: bytes.Clone(sigma0),
+		Rounds: make([]TranscriptRound, rounds)}
+}
+
+func (tr *Transcript) challenges(alphas []uint8, h1 []byte) {
+	if tr == nil {
+		return
+	}
+	tr.Alphas = bytes.Clone(alphas)
+	tr.H1 = bytes.Clone(h1)
+}
+
+func (tr *Transcript) round(i int, b byte, c0, c1 []byte) {
+	if tr == nil || i >= len(tr.Rounds) {
+		return
+	}
+	tr.Rounds[i] = TranscriptRound{Bit: b, C0: bytes.Clone(c0), C1: bytes.Clone(c1)}
+}
+
+func (tr *Transcript) recomputed(sigma0, h1 []byte) {
+	if tr == nil {
+		return
+	}
+	tr.Sigma0Prime = bytes.Clone(sigma0)
+	tr.H1Prime = bytes.Clone(h1)
+}
@@ -3,15 +3,39 @@ package crypto
 import (
 	"bytes"
 	"crypto/rand"
+	"errors"
+	constants "mqat/const"
 	"mqat/math"
 )
 
-func NewUOV(m, n, pk_seed_len, sk_seed_len int) *UOV {
+var (
+	ErrKeyMismatch = errors.New("crypto: secret and public keys do not match")
+	ErrSignFailed  = errors.New("crypto: could not sign")
+	ErrFault       = errors.New("crypto: fault detected while signing")
+)
+
+func NewUOV(m, n, pk_seed_len, sk_seed_len int, opts ...Option) *UOV {
+	return NewUOVOver(math.GF256, m, n, pk_seed_len, sk_seed_len, opts...)
+}
+
+func NewUOVOver(F math.Field, m, n, pk_seed_len, sk_seed_len int, opts ...Option) *UOV {
+	if m <= 0 || n <= 0 || m > n || pk_seed_len <= 0 || sk_seed_len <= 0 {
+		return nil
+	}
+	o := newOptions(opts)
+	if !o.accepts("UOV", optPRG) {
+		return nil
+	}
+	if !secure("UOV", EstimateUOV(F.Order(), m, n), o) {
+		return nil
+	}
 	uov := new(UOV)
+	uov.Field = F
 	uov.M = m
 	uov.N = n
 	uov.PkSeedLen = pk_seed_len
 	uov.SkSeedLen = sk_seed_len
+	uov.PRG = o.prg
 	return uov
 }
 
@@ -31,53 +55,79 @@ func (uov *UOV) KeyGen() (*UOVSecretKey, *UOVPublicKey) {
 	}
 	uov_sk.Seed = bytes.Clone(uov_seed_sk)
 	uov_pk.Seed = bytes.Clone(uov_seed_pk)
+	uov_sk.PkSeed = bytes.Clone(uov_seed_pk)
 
-	O := Nrand256(uov.M*(uov.N-uov.M), uov_seed_sk)
+	O := domUOVOil.sample(uov.Field, uov.M*(uov.N-uov.M), uov_seed_sk)
 	if O == nil {
 		return nil, nil
 	}
 	uov_sk.O = O
 
-	P1s_output_len := uov.M * (uov.N - uov.M) * (uov.N - uov.M + 1) / 2
-	P2s_output_len := uov.M * uov.M * (uov.N - uov.M)
-	total_len := P1s_output_len + P2s_output_len
-	Pi12 := Nrand128(total_len, uov_seed_pk)
-	Pi1 := Pi12[:P1s_output_len]
-	Pi2 := Pi12[P1s_output_len:]
-	if Pi1 == nil || Pi2 == nil {
+	Pi1, Pi2 := uov.expandP(uov_seed_pk)
+	if Pi1 == nil {
 		return nil, nil
 	}
-	uov_sk.Si = deriveSi(O, Pi1, Pi2, uov.M, uov.N)
+	uov_sk.Si = deriveSi(uov.Field, O, Pi1, Pi2, uov.M, uov.M, uov.N)
 
-	Pi3 := derivePi3(O, Pi1, Pi2, uov.M, uov.N)
+	Pi3 := derivePi3(uov.Field, O, Pi1, Pi2, uov.M, uov.M, uov.N)
 	if Pi3 == nil {
 		return nil, nil
 	}
 	uov_pk.P1i = Pi1
 	uov_pk.P2i = Pi2
 	uov_pk.P3i = Pi3
-	uov_sk.P1i = Pi1
+	// the secret key keeps its own copy, which Destroy wipes
+	uov_sk.P1i = bytes.Clone(Pi1)
 	return uov_sk, uov_pk
 }
 
+// Sign returns a preimage of message, which must be a target of M field
+// elements; SignMessage signs arbitrary bytes.
 func (uov *UOV) Sign(message []uint8, sk *UOVSecretKey) []uint8 {
+	sig, _ := uov.sign(message, sk, false)
+	return sig
+}
+
+// SignChecked signs like Sign with fault countermeasures: the signature is
+// evaluated back with the secret key, which redoes the elimination and the
+// multiplication by O, then verified under pk before it is released. A
+// faulty signature may leak O, so any mismatch returns ErrFault and no
+// signature.
+func (uov *UOV) SignChecked(message []uint8, sk *UOVSecretKey, pk *UOVPublicKey) ([]uint8, error) {
+	if pk == nil {
+		return nil, ErrUOVKey
+	}
+	sig, err := uov.sign(message, sk, true)
+	if err != nil {
+		return nil, err
+	}
+	if !uov.Verify(message, sig, pk) {
+		return nil, ErrFault
+	}
+	return sig, nil
+}
+
+func (uov *UOV) sign(message []uint8, sk *UOVSecretKey, checked bool) ([]uint8, error) {
+	if len(message) != uov.M {
+		return nil, ErrSignFailed
+	}
+	if uov.ValidateSecretKey(sk, nil) != nil {
+		return nil, ErrUOVKey
+	}
+	F := uov.Field
 	lenSi := (uov.N - uov.M) * uov.M
 	lenP1i := (uov.N - uov.M) * (uov.N - uov.M + 1) / 2
 	for ctr := 0; ctr < 256; ctr++ {
-		seed := append(message, sk.Seed...)
-		seed = append(seed, byte(ctr))
-		v := Nrand256(uov.N-uov.M, seed)
-		L := make([]uint8, 0)
+		v := domUOVVinegar.sample(F, uov.N-uov.M, message, sk.Seed, []byte{byte(ctr)})
+		L := make([]uint8, 0, uov.M*uov.M)
 		vec := math.NewVector(v)
 		vec_t := math.T(vec)
 		for i := 0; i < uov.M; i++ {
 			Si := math.NewDenseMatrix(uov.N-uov.M, uov.M,
 				sk.Si[i*lenSi:(i+1)*lenSi])
-			res := math.MulMat(vec_t, Si)
-			if len(res.Data) != uov.M {
-				return nil
-			}
+			res := math.MulMatOver(F, vec_t, Si)
 			L = append(L, res.Data...)
+			Wipe(res.Data)
 		}
 		matL := math.NewDenseMatrix(uov.M, uov.M, L)
 		y := bytes.Clone(message)
@@ -85,83 +135,247 @@ func (uov *UOV) Sign(message []uint8, sk *UOVSecretKey) []uint8 {
 			P1i := math.NewUpperTriangle(
 				math.NewDenseMatrix(uov.N-uov.M, uov.N-uov.M,
 					sk.P1i[i*lenP1i:(i+1)*lenP1i]))
-			res := math.MulMat(math.MulMat(vec_t, P1i), vec)
-			if len(res.Data) != 1 {
-				return nil
-			}
-			y[i] ^= res.Data[0]
+			vP1 := math.MulMatOver(F, vec_t, P1i)
+			res := math.MulMatOver(F, vP1, vec)
+			y[i] = F.Sub(y[i], res.Data[0])
+			Wipe(vP1.Data, res.Data)
 		}
 		vecY := math.NewVector(y)
-		x := math.Solve(matL, vecY)
+		x := math.SolveOver(F, matL, vecY)
+		Wipe(L, y)
 		if x.Data == nil {
+			Wipe(v)
 			continue
 		}
-		O := sk.O
+		O := append(make([]uint8, 0, uov.N*uov.M), sk.O...)
 		for i := 0; i < uov.M; i++ {
 			e := make([]uint8, uov.M)
 			e[i] = 1
 			O = append(O, e...)
 		}
 		OBar := math.NewDenseMatrix(uov.N, uov.M, O)
-		res := math.MulMat(OBar, x)
-		if len(res.Data) != uov.N {
-			return nil
-		}
+		res := math.MulMatOver(F, OBar, x)
 
-		v = append(v, make([]uint8, uov.M)...)
+		s := make([]uint8, uov.N)
+		copy(s, v)
 		for i := 0; i < uov.N; i++ {
-			v[i] ^= res.Data[i]
+			s[i] = F.Add(s[i], res.Data[i])
+		}
+		Wipe(O, res.Data, x.Data, v)
+		v = s
+		if checked && !bytes.Equal(uov.secretEval(sk, v), message) {
+			Wipe(v)
+			return nil, ErrFault
 		}
-		return v
+		return v, nil
 	}
-	return nil
+	return nil, ErrSignFailed
 }
 
 func (uov *UOV) Verify(message, signature []uint8, pk *UOVPublicKey) bool {
-	res := math.MQP(pk.P1i, pk.P2i, pk.P3i, signature, uov.M)
-	return bytes.Equal(message, res)
+	P, err := uov.PublicMap(pk)
+	if err != nil {
+		return false
+	}
+	res := P.Eval(signature)
+	return res != nil && bytes.Equal(message, res)
+}
+
+// SignMessage signs a message of any length: it samples a salt and signs the
+// target hashed from the message and the salt, returning the signature and
+// the salt.
+func (uov *UOV) SignMessage(message []byte, sk *UOVSecretKey) ([]uint8, []byte) {
+	salt := make([]byte, constants.SALT_LEN/8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil
+	}
+	sig := uov.Sign(uov.target(message, salt), sk)
+	if sig == nil {
+		return nil, nil
+	}
+	return sig, salt
+}
+
+func (uov *UOV) VerifyMessage(message []byte, signature []uint8, salt []byte, pk *UOVPublicKey) bool {
+	return len(salt) == constants.SALT_LEN/8 && uov.Verify(uov.target(message, salt), signature, pk)
+}
+
+// ValidatePublicKey checks the dimensions of pk, that its entries are field
+// elements and, if its seed is kept, that P1 and P2 are its expansion.
+func (uov *UOV) ValidatePublicKey(pk *UOVPublicKey) error {
+	m, v := uov.M, uov.N-uov.M
+	if pk == nil || len(pk.P1i) != m*v*(v+1)/2 || len(pk.P2i) != m*v*m || len(pk.P3i) != m*m*(m+1)/2 {
+		return ErrUOVKey
+	}
+	if !inField(uov.Field, pk.P1i, pk.P2i, pk.P3i) {
+		return ErrUOVKey
+	}
+	if pk.Seed != nil {
+		Pi1, Pi2 := uov.expandP(pk.Seed)
+		if !bytes.Equal(Pi1, pk.P1i) || !bytes.Equal(Pi2, pk.P2i) {
+			return ErrUOVKey
+		}
+	}
+	return nil
+}
+
+// ValidateSecretKey checks the dimensions of sk and, if pk is not nil, that
+// the two keys form a pair: same P1, Si = deriveSi(O, P1, P2) and P3 the
+// one derived from O.
+func (uov *UOV) ValidateSecretKey(sk *UOVSecretKey, pk *UOVPublicKey) error {
+	m, v := uov.M, uov.N-uov.M
+	if sk == nil || len(sk.Seed) == 0 || len(sk.O) != v*m || len(sk.Si) != m*v*m || len(sk.P1i) != m*v*(v+1)/2 {
+		return ErrUOVKey
+	}
+	if !inField(uov.Field, sk.O, sk.Si, sk.P1i) {
+		return ErrUOVKey
+	}
+	if pk == nil {
+		return nil
+	}
+	if err := uov.ValidatePublicKey(pk); err != nil {
+		return err
+	}
+	if !bytes.Equal(sk.P1i, pk.P1i) ||
+		!bytes.Equal(sk.Si, deriveSi(uov.Field, sk.O, pk.P1i, pk.P2i, m, m, uov.N)) ||
+		!bytes.Equal(pk.P3i, derivePi3(uov.Field, sk.O, pk.P1i, pk.P2i, m, m, uov.N)) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+// PublicKeyFromSecret rebuilds the public key of sk, recovering P2 from
+// Si = (P1 + P1^T) O + P2. The seed of the public key is only set if sk
+// keeps it, in which case it must expand to P1 and P2.
+func (uov *UOV) PublicKeyFromSecret(sk *UOVSecretKey) (*UOVPublicKey, error) {
+	if err := uov.ValidateSecretKey(sk, nil); err != nil {
+		return nil, err
+	}
+	F := uov.Field
+	m, n, v := uov.M, uov.N, uov.N-uov.M
+	zero := make([]uint8, m*v*m)
+	// deriveSi with P2 = 0 gives (P1 + P1^T) O
+	P1O := deriveSi(F, sk.O, sk.P1i, zero, m, m, n)
+	pk := &UOVPublicKey{P1i: bytes.Clone(sk.P1i), P2i: make([]uint8, len(zero))}
+	for i := range pk.P2i {
+		pk.P2i[i] = F.Sub(sk.Si[i], P1O[i])
+	}
+	pk.P3i = derivePi3(F, sk.O, pk.P1i, pk.P2i, m, m, n)
+	if sk.PkSeed != nil {
+		pk.Seed = bytes.Clone(sk.PkSeed)
+		if err := uov.ValidatePublicKey(pk); err != nil {
+			return nil, err
+		}
+	}
+	return pk, nil
+}
+
+// PublicMap returns the public key as an MQSystem over the N variables.
+func (uov *UOV) PublicMap(pk *UOVPublicKey) (*math.MQSystem, error) {
+	return math.NewMQSystemFromUOV(uov.Field, uov.M, uov.N, pk.P1i, pk.P2i, pk.P3i)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // Helpers
 ////////////////////////////////////////////////////////////////////////////////
 
-func deriveSi(O, Pi1, Pi2 []uint8, m, n int) []uint8 {
-	res := make([]uint8, 0)
+// expandP expands P1 and P2, one block per equation, from the public seed.
+func (uov *UOV) expandP(seed []byte) ([]uint8, []uint8) {
+	m, v := uov.M, uov.N-uov.M
+	lenP1 := m * v * (v + 1) / 2
+	Pi12 := domUOVPublic.expand(uov.PRG, uov.Field, lenP1+m*m*v, seed)
+	if Pi12 == nil {
+		return nil, nil
+	}
+	return Pi12[:lenP1], Pi12[lenP1:]
+}
+
+// inField reports whether all the entries are elements of F.
+func inField(F math.Field, entries ...[]uint8) bool {
+	for _, e := range entries {
+		for _, a := range e {
+			if int(a) >= F.Order() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// secretEval computes P(s) with the secret key: for s = (u + O x, x),
+// P(s) = P1(u) + u^T S_i x as P vanishes on the oil space.
+func (uov *UOV) secretEval(sk *UOVSecretKey, s []uint8) []uint8 {
+	F := uov.Field
+	m, v := uov.M, uov.N-uov.M
+	x := math.NewVector(s[v:])
+	Ox := math.MulMatOver(F, math.NewDenseMatrix(v, m, sk.O), x)
+	u := make([]uint8, v)
+	for i := range u {
+		u[i] = F.Sub(s[i], Ox.Data[i])
+	}
+	uvec := math.NewVector(u)
+	ut := math.T(uvec)
+	lenP1i := v * (v + 1) / 2
+	res := make([]uint8, m)
+	for i := 0; i < m; i++ {
+		P1 := math.NewUpperTriangle(math.NewDenseMatrix(v, v, sk.P1i[i*lenP1i:(i+1)*lenP1i]))
+		Si := math.NewDenseMatrix(v, m, sk.Si[i*v*m:(i+1)*v*m])
+		q := math.MulMatOver(F, math.MulMatOver(F, ut, P1), uvec)
+		l := math.MulMatOver(F, math.MulMatOver(F, ut, Si), x)
+		res[i] = F.Add(q.Data[0], l.Data[0])
+	}
+	Wipe(u, Ox.Data)
+	return res
+}
+
+// target hashes a message and its salt to the M field elements signed.
+func (uov *UOV) target(message, salt []byte) []uint8 {
+	return domUOVMessage.sample(uov.Field, uov.M, message, salt)
+}
+
+// deriveSi computes S_i = (P1_i + P1_i^T) O + P2_i for the m equations, O
+// being the (n-o) x o matrix of the oil space.
+func deriveSi(F math.Field, O, Pi1, Pi2 []uint8, m, o, n int) []uint8 {
+	v := n - o
+	res := make([]uint8, 0, m*v*o)
+	OM := math.NewDenseMatrix(v, o, O)
 	for i := 0; i < m; i++ {
-		P1 := math.NewUpperTriangle(math.NewDenseMatrix(n-m, n-m, Pi1[i*(n-m)*(n-m+1)/2:(i+1)*(n-m)*(n-m+1)/2]))
+		P1 := math.NewUpperTriangle(math.NewDenseMatrix(v, v, Pi1[i*v*(v+1)/2:(i+1)*v*(v+1)/2]))
 		P1T := math.T(P1)
-		OM := math.NewDenseMatrix(n-m, m, O)
-		P2 := math.NewDenseMatrix(n-m, m, Pi2[i*(n-m)*m:(i+1)*(n-m)*m])
-		Si := math.AddMat(math.MulMat(math.AddMat(P1, P1T), OM), P2)
+		P2 := math.NewDenseMatrix(v, o, Pi2[i*v*o:(i+1)*v*o])
+		Si := math.AddMatOver(F, math.MulMatOver(F, math.AddMatOver(F, P1, P1T), OM), P2)
 		res = append(res, Si.Data...)
 	}
-	if len(res) != m*(n-m)*m {
+	if len(res) != m*v*o {
 		return nil
 	}
 	return res
 }
 
-func derivePi3(O, Pi1, Pi2 []uint8, m, n int) []uint8 {
-	Omat := math.NewDenseMatrix(n-m, m, O)
+// derivePi3 computes the P3 blocks for which the m equations vanish on the
+// oil space.
+func derivePi3(F math.Field, O, Pi1, Pi2 []uint8, m, o, n int) []uint8 {
+	v := n - o
+	Omat := math.NewDenseMatrix(v, o, O)
 	OmatT := math.T(Omat)
-	lenP1 := (n - m) * (n - m + 1) / 2
-	lenP2 := (n - m) * m
+	lenP1 := v * (v + 1) / 2
+	lenP2 := v * o
 	res := make([]uint8, 0)
 	for i := 0; i < m; i++ {
-		P1 := math.NewUpperTriangle(math.NewDenseMatrix(n-m, n-m, Pi1[i*lenP1:(i+1)*lenP1]))
-		P2 := math.NewDenseMatrix(n-m, m, Pi2[i*lenP2:(i+1)*lenP2])
-		M := math.AddMat(math.MulMat(math.MulMat(OmatT, P1), Omat), math.MulMat(OmatT, P2))
+		P1 := math.NewUpperTriangle(math.NewDenseMatrix(v, v, Pi1[i*lenP1:(i+1)*lenP1]))
+		P2 := math.NewDenseMatrix(v, o, Pi2[i*lenP2:(i+1)*lenP2])
+		M := math.AddMatOver(F, math.MulMatOver(F, math.MulMatOver(F, OmatT, P1), Omat), math.MulMatOver(F, OmatT, P2))
 		r, c := M.Dims()
-		if r != m || c != m {
+		if r != o || c != o {
 			return nil
 		}
 		MT := math.T(M)
-		M_plus_MT := math.AddMat(M, MT)
-		for i := 0; i < m; i++ {
-			res = append(res, M.At(i, i))
-			for j := i + 1; j < m; j++ {
-				res = append(res, M_plus_MT.At(i, j))
+		M_plus_MT := math.AddMatOver(F, M, MT)
+		// P3 = -Upper(M) so that P vanishes on the oil space
+		for i := 0; i < o; i++ {
+			res = append(res, F.Neg(M.At(i, i)))
+			for j := i + 1; j < o; j++ {
+				res = append(res, F.Neg(M_plus_MT.At(i, j)))
 			}
 		}
 	}
@@ -0,0 +1,457 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	constants "mqat/const"
+	"mqat/math"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// UOV as specified for round 2 of the NIST additional signatures (Beullens
+// et al., UOV specification v2.0). With v = n - m, the public matrices are
+// stored m-parallel: entry (i, j) of P1 (v x v, upper triangular), P2
+// (v x m), P3 (m x m, upper triangular) and S (v x m) is the vector of its m
+// coefficients, one per equation, and the triangular matrices list their
+// entries row by row. The formats are
+//
+//	classic:  pk = P1 | P2 | P3    sk = seed_sk | O | P1 | S
+//	pkc:      pk = seed_pk | P3    sk = seed_sk | O | P1 | S
+//	pkc+skc:  pk = seed_pk | P3    sk = seed_sk
+//
+// with seed_pk | O = SHAKE256(seed_sk), O stored column by column, and
+// P1 | P2 = AES-128-CTR(seed_pk), keyed by the raw seed. A signature is
+// s | salt with P(s) = SHAKE256(M | salt). The hash inputs are those of the
+// specification, not domains, so that the KAT files of the submission apply.
+
+var ErrUOVKey = errors.New("crypto: malformed UOV key")
+
+// UOVVariant is a key format of the specification.
+type UOVVariant int
+
+const (
+	UOVClassic UOVVariant = iota + 1
+	UOVPkc                // compressed public key
+	UOVPkcSkc             // compressed public and secret keys
+)
+
+func (variant UOVVariant) String() string {
+	switch variant {
+	case UOVClassic:
+		return "classic"
+	case UOVPkc:
+		return "pkc"
+	case UOVPkcSkc:
+		return "pkc+skc"
+	}
+	return "unknown"
+}
+
+// UOVParams is a parameter set of the specification.
+type UOVParams struct {
+	Name  string
+	Field math.Field
+	N, M  int
+}
+
+var (
+	UOV_Is  = UOVParams{Name: "uov-Is", Field: math.GF16, N: 160, M: 64}
+	UOV_Ip  = UOVParams{Name: "uov-Ip", Field: math.GF256, N: 112, M: 44}
+	UOV_III = UOVParams{Name: "uov-III", Field: math.GF256, N: 184, M: 72}
+	UOV_V   = UOVParams{Name: "uov-V", Field: math.GF256, N: 244, M: 96}
+)
+
+func (p UOVParams) New(variant UOVVariant) *NISTUOV {
+	if variant < UOVClassic || variant > UOVPkcSkc {
+		return nil
+	}
+	return &NISTUOV{UOVParams: p, Variant: variant}
+}
+
+// WithNISTUOV makes MQAT issue tokens with the NIST UOV parameter set of its
+// field and dimensions, e.g. uov-Is for MQAT_GF16_NISTUOV, its keys in the
+// given format.
+func WithNISTUOV(variant UOVVariant) Option {
+	return func(o *options) { o.nist, o.set = variant, o.set|optNISTUOV }
+}
+
+func (uov *NISTUOV) PublicKeySize() int {
+	if uov.Variant == UOVClassic {
+		return uov.lenP1() + uov.lenP2() + uov.lenP3()
+	}
+	return nistPkSeedLen + uov.lenP3()
+}
+
+func (uov *NISTUOV) SecretKeySize() int {
+	if uov.Variant == UOVPkcSkc {
+		return nistSkSeedLen
+	}
+	return nistSkSeedLen + uov.lenO() + uov.lenP1() + uov.lenP2()
+}
+
+func (uov *NISTUOV) SignatureSize() int {
+	return uov.Field.PackedLen(uov.N) + nistSaltLen
+}
+
+// KeyGen returns a secret key and a public key in the format of uov.
+func (uov *NISTUOV) KeyGen() ([]byte, []byte, error) {
+	seed_sk := make([]byte, nistSkSeedLen)
+	if _, err := uov.random().Read(seed_sk); err != nil {
+		return nil, nil, err
+	}
+	defer Wipe(seed_sk)
+	key, err := uov.expandSK(seed_sk)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer key.wipe()
+	sk, pk := uov.encode(key)
+	return sk, pk, nil
+}
+
+// PublicKeyFromSecret rebuilds the public key of sk from its seed, checking
+// that the rest of sk is the expansion of the seed.
+func (uov *NISTUOV) PublicKeyFromSecret(sk []byte) ([]byte, error) {
+	if len(sk) != uov.SecretKeySize() {
+		return nil, ErrUOVKey
+	}
+	key, err := uov.expandSK(sk[:nistSkSeedLen])
+	if err != nil {
+		return nil, err
+	}
+	defer key.wipe()
+	expanded, pk := uov.encode(key)
+	defer Wipe(expanded)
+	if !bytes.Equal(sk, expanded) {
+		return nil, ErrUOVKey
+	}
+	return pk, nil
+}
+
+// Sign signs message with a fresh salt.
+func (uov *NISTUOV) Sign(message, sk []byte) ([]byte, error) {
+	key, err := uov.secretKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	defer key.wipe()
+	salt := make([]byte, nistSaltLen)
+	if _, err := uov.random().Read(salt); err != nil {
+		return nil, err
+	}
+	s := uov.sign(key, uov.digest(message, salt), message, salt)
+	if s == nil {
+		return nil, errors.New("crypto: no UOV signature found")
+	}
+	return append(uov.Field.Pack(s), salt...), nil
+}
+
+func (uov *NISTUOV) Verify(message, sig, pk []byte) bool {
+	if len(sig) != uov.SignatureSize() {
+		return false
+	}
+	P, err := uov.PublicMap(pk)
+	if err != nil {
+		return false
+	}
+	lenS := uov.Field.PackedLen(uov.N)
+	return bytes.Equal(P.Eval(uov.Field.Unpack(sig[:lenS], uov.N)), uov.digest(message, sig[lenS:]))
+}
+
+// SignTarget returns a preimage s of the target t under the public map,
+// without hashing: MQAT signs blinded targets which are already uniform.
+func (uov *NISTUOV) SignTarget(t []uint8, sk []byte) []uint8 {
+	key, err := uov.secretKey(sk)
+	if err != nil || len(t) != uov.M {
+		return nil
+	}
+	defer key.wipe()
+	return uov.sign(key, t, uov.Field.Pack(t))
+}
+
+// PublicMap returns the public key as an MQSystem over the N variables.
+func (uov *NISTUOV) PublicMap(pk []byte) (*math.MQSystem, error) {
+	if len(pk) != uov.PublicKeySize() {
+		return nil, ErrUOVKey
+	}
+	F := uov.Field
+	var P1, P2 []uint8
+	if uov.Variant == UOVClassic {
+		P1 = F.Unpack(pk[:uov.lenP1()], uov.lenP1()/uov.lenVec()*uov.M)
+		P2 = F.Unpack(pk[uov.lenP1():uov.lenP1()+uov.lenP2()], uov.lenP2()/uov.lenVec()*uov.M)
+		pk = pk[uov.lenP1()+uov.lenP2():]
+	} else {
+		var err error
+		if P1, P2, err = uov.expandP(pk[:nistPkSeedLen]); err != nil {
+			return nil, err
+		}
+		pk = pk[nistPkSeedLen:]
+	}
+	P3 := F.Unpack(pk, uov.lenP3()/uov.lenVec()*uov.M)
+	m := uov.M
+	return math.NewMQSystemFromUOV(F, m, uov.N, perEquation(P1, m), perEquation(P2, m), perEquation(P3, m))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	nistSkSeedLen = constants.UOV_SK_SEED_LEN / 8
+	nistPkSeedLen = constants.UOV_PK_SEED_LEN / 8
+	nistSaltLen   = constants.SALT_LEN / 8
+)
+
+// nistUOVKey is an expanded key, matrices unpacked and m-parallel, O v x m
+// row-major. P2 and P3 are only derived by KeyGen.
+type nistUOVKey struct {
+	seed_sk, seed_pk []byte
+	O                []uint8
+	P1, P2, P3, S    []uint8
+}
+
+func (uov *NISTUOV) lenVec() int { return uov.Field.PackedLen(uov.M) }
+func (uov *NISTUOV) lenP1() int  { v := uov.N - uov.M; return v * (v + 1) / 2 * uov.lenVec() }
+func (uov *NISTUOV) lenP2() int  { return (uov.N - uov.M) * uov.M * uov.lenVec() }
+func (uov *NISTUOV) lenP3() int  { return uov.M * (uov.M + 1) / 2 * uov.lenVec() }
+func (uov *NISTUOV) lenO() int   { return uov.M * uov.Field.PackedLen(uov.N-uov.M) }
+
+func (uov *NISTUOV) random() io.Reader {
+	if uov.Rand != nil {
+		return uov.Rand
+	}
+	return rand.Reader
+}
+
+// digest is the target SHAKE256(M | salt).
+func (uov *NISTUOV) digest(message, salt []byte) []uint8 {
+	xof := sha3.NewShake256()
+	xof.Write(message)
+	xof.Write(salt)
+	t := make([]byte, uov.lenVec())
+	xof.Read(t)
+	return uov.Field.Unpack(t, uov.M)
+}
+
+// expandP expands P1 and P2 from seed_pk.
+func (uov *NISTUOV) expandP(seed_pk []byte) ([]uint8, []uint8, error) {
+	F := uov.Field
+	P12 := make([]byte, uov.lenP1()+uov.lenP2())
+	if _, err := AES128CTR.Expand(seed_pk).Read(P12); err != nil {
+		return nil, nil, err
+	}
+	return F.Unpack(P12[:uov.lenP1()], uov.lenP1()/uov.lenVec()*uov.M),
+		F.Unpack(P12[uov.lenP1():], uov.lenP2()/uov.lenVec()*uov.M), nil
+}
+
+// expandSK derives the whole key from seed_sk:
+//
+//	S = (P1 + P1^T) O + P2,  P3 = -Upper(O^T P1 O + O^T P2).
+func (uov *NISTUOV) expandSK(seed_sk []byte) (*nistUOVKey, error) {
+	F := uov.Field
+	m, v := uov.M, uov.N-uov.M
+	xof := sha3.NewShake256()
+	xof.Write(seed_sk)
+	key := &nistUOVKey{seed_sk: bytes.Clone(seed_sk), seed_pk: make([]byte, nistPkSeedLen)}
+	Ob := make([]byte, uov.lenO())
+	xof.Read(key.seed_pk)
+	xof.Read(Ob)
+	key.O = uov.decodeO(Ob)
+	Wipe(Ob)
+	var err error
+	if key.P1, key.P2, err = uov.expandP(key.seed_pk); err != nil {
+		return nil, err
+	}
+
+	// T = Upper(P1) O + P2, S = T + Lower(P1) O
+	T := bytes.Clone(key.P2)
+	L := make([]uint8, v*m*m)
+	defer Wipe(T, L)
+	for i := 0; i < v; i++ {
+		for l := i; l < v; l++ {
+			p := key.P1[triIndex(i, l, v)*m : (triIndex(i, l, v)+1)*m]
+			for j := 0; j < m; j++ {
+				axpy(F, T[(i*m+j)*m:(i*m+j+1)*m], key.O[l*m+j], p)
+				axpy(F, L[(l*m+j)*m:(l*m+j+1)*m], key.O[i*m+j], p)
+			}
+		}
+	}
+	key.S = make([]uint8, len(T))
+	for i := range T {
+		key.S[i] = F.Add(T[i], L[i])
+	}
+
+	// M = O^T T
+	M := make([]uint8, m*m*m)
+	defer Wipe(M)
+	for a := 0; a < m; a++ {
+		for i := 0; i < v; i++ {
+			for b := 0; b < m; b++ {
+				axpy(F, M[(a*m+b)*m:(a*m+b+1)*m], key.O[i*m+a], T[(i*m+b)*m:(i*m+b+1)*m])
+			}
+		}
+	}
+	key.P3 = make([]uint8, 0, m*(m+1)/2*m)
+	for a := 0; a < m; a++ {
+		for b := a; b < m; b++ {
+			for k := 0; k < m; k++ {
+				c := M[(a*m+b)*m+k]
+				if a != b {
+					c = F.Add(c, M[(b*m+a)*m+k])
+				}
+				key.P3 = append(key.P3, F.Neg(c))
+			}
+		}
+	}
+	return key, nil
+}
+
+// encode encodes the keys in the format of uov.
+func (uov *NISTUOV) encode(key *nistUOVKey) ([]byte, []byte) {
+	F := uov.Field
+	var sk, pk []byte
+	switch uov.Variant {
+	case UOVClassic:
+		pk = append(append(F.Pack(key.P1), F.Pack(key.P2)...), F.Pack(key.P3)...)
+	default:
+		pk = append(bytes.Clone(key.seed_pk), F.Pack(key.P3)...)
+	}
+	switch uov.Variant {
+	case UOVPkcSkc:
+		sk = bytes.Clone(key.seed_sk)
+	default:
+		sk = append(bytes.Clone(key.seed_sk), uov.encodeO(key.O)...)
+		sk = append(append(sk, F.Pack(key.P1)...), F.Pack(key.S)...)
+	}
+	return sk, pk
+}
+
+// secretKey decodes sk, expanding it in the pkc+skc format.
+func (uov *NISTUOV) secretKey(sk []byte) (*nistUOVKey, error) {
+	if len(sk) != uov.SecretKeySize() {
+		return nil, ErrUOVKey
+	}
+	if uov.Variant == UOVPkcSkc {
+		return uov.expandSK(sk)
+	}
+	F := uov.Field
+	key := &nistUOVKey{seed_sk: bytes.Clone(sk[:nistSkSeedLen])}
+	sk = sk[nistSkSeedLen:]
+	key.O = uov.decodeO(sk[:uov.lenO()])
+	sk = sk[uov.lenO():]
+	key.P1 = F.Unpack(sk[:uov.lenP1()], uov.lenP1()/uov.lenVec()*uov.M)
+	key.S = F.Unpack(sk[uov.lenP1():], uov.lenP2()/uov.lenVec()*uov.M)
+	return key, nil
+}
+
+// sign finds s with P(s) = t, the vinegar variables being expanded from the
+// prefix, the secret seed and a counter.
+func (uov *NISTUOV) sign(key *nistUOVKey, t []uint8, prefix ...[]byte) []uint8 {
+	F := uov.Field
+	m, v := uov.M, uov.N-uov.M
+	xof := sha3.NewShake256()
+	for _, p := range prefix {
+		xof.Write(p)
+	}
+	xof.Write(key.seed_sk)
+	vb := make([]byte, F.PackedLen(v))
+	defer Wipe(vb)
+	for ctr := 0; ctr < 256; ctr++ {
+		h := xof.Clone()
+		h.Write([]byte{byte(ctr)})
+		h.Read(vb)
+		x := F.Unpack(vb, v)
+
+		// y = t - v^T P1 v, L = (v^T S_k)_k
+		y := make([]uint8, m)
+		for i := 0; i < v; i++ {
+			for j := i; j < v; j++ {
+				axpy(F, y, F.Mul(x[i], x[j]), key.P1[triIndex(i, j, v)*m:(triIndex(i, j, v)+1)*m])
+			}
+		}
+		for k := range y {
+			y[k] = F.Sub(t[k], y[k])
+		}
+		Lt := make([]uint8, m*m)
+		for i := 0; i < v; i++ {
+			for j := 0; j < m; j++ {
+				axpy(F, Lt[j*m:(j+1)*m], x[i], key.S[(i*m+j)*m:(i*m+j+1)*m])
+			}
+		}
+		xo := math.SolveOver(F, math.T(math.NewDenseMatrix(m, m, Lt)), math.NewVector(y))
+		Wipe(y, Lt)
+		if xo.Data == nil {
+			Wipe(x)
+			continue
+		}
+
+		// s = (v + O x_o, x_o)
+		for i := 0; i < v; i++ {
+			for j := 0; j < m; j++ {
+				x[i] = F.Add(x[i], F.Mul(key.O[i*m+j], xo.Data[j]))
+			}
+		}
+		s := append(x, xo.Data...)
+		Wipe(xo.Data)
+		return s
+	}
+	return nil
+}
+
+// encodeO packs O column by column.
+func (uov *NISTUOV) encodeO(O []uint8) []byte {
+	m, v := uov.M, uov.N-uov.M
+	out := make([]byte, 0, uov.lenO())
+	col := make([]uint8, v)
+	for j := 0; j < m; j++ {
+		for i := range col {
+			col[i] = O[i*m+j]
+		}
+		out = append(out, uov.Field.Pack(col)...)
+	}
+	return out
+}
+
+func (uov *NISTUOV) decodeO(data []byte) []uint8 {
+	m, v := uov.M, uov.N-uov.M
+	lenCol := uov.Field.PackedLen(v)
+	O := make([]uint8, v*m)
+	for j := 0; j < m; j++ {
+		for i, e := range uov.Field.Unpack(data[j*lenCol:(j+1)*lenCol], v) {
+			O[i*m+j] = e
+		}
+	}
+	return O
+}
+
+// triIndex is the index of entry (i, j), i <= j, of an n x n upper
+// triangular matrix stored row by row.
+func triIndex(i, j, n int) int {
+	return i*n + j - i*(i+1)/2
+}
+
+// axpy adds c x to y.
+func axpy(F math.Field, y []uint8, c uint8, x []uint8) {
+	if c == 0 {
+		return
+	}
+	for k := range y {
+		y[k] = F.Add(y[k], F.Mul(c, x[k]))
+	}
+}
+
+// perEquation turns m-parallel entries into one block of entries per
+// equation.
+func perEquation(entries []uint8, m int) []uint8 {
+	n := len(entries) / m
+	out := make([]uint8, len(entries))
+	for e := 0; e < n; e++ {
+		for k := 0; k < m; k++ {
+			out[k*n+e] = entries[e*m+k]
+		}
+	}
+	return out
+}
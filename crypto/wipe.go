@@ -0,0 +1,83 @@
+package crypto
+
+// Erasure of secret material. Destroy overwrites a secret key with zeros and
+// drops its buffers, after which signing with it fails instead of using a
+// zero key. The schemes also wipe the secret temporaries they allocate
+// (vinegar, linear systems, the MQDSS round randomness and shares, expanded
+// NIST keys) once a signature is computed. This is best effort: Go gives no
+// control over copies made by the runtime or inside the math package, so it
+// shortens how long secrets stay in memory rather than bounding it.
+
+// Wipe overwrites the buffers with zeros, e.g. z* and the issuer's response
+// once User1 has finalized the token.
+func Wipe(bufs ...[]uint8) {
+	for _, b := range bufs {
+		if wipeHook != nil {
+			wipeHook(b)
+		}
+		clear(b)
+	}
+}
+
+// wipeHook, set by the tests, sees every buffer Wipe clears just before it
+// is cleared.
+var wipeHook func([]uint8)
+
+func (sk *UOVSecretKey) Destroy() {
+	if sk == nil {
+		return
+	}
+	Wipe(sk.Seed, sk.O, sk.Si, sk.P1i)
+	*sk = UOVSecretKey{}
+}
+
+func (sk *MAYOSecretKey) Destroy() {
+	if sk == nil {
+		return
+	}
+	Wipe(sk.Seed, sk.O, sk.Si, sk.P1i)
+	*sk = MAYOSecretKey{}
+}
+
+// Destroy wipes the witness; the public key stays usable.
+func (sk *MQDSSSecretKey) Destroy() {
+	if sk == nil {
+		return
+	}
+	Wipe(sk.S)
+	sk.S = nil
+}
+
+func (sk *MQATSecretKey) Destroy() {
+	if sk == nil {
+		return
+	}
+	sk.uov_sk.Destroy()
+	sk.mayo_sk.Destroy()
+	Wipe(sk.nist_sk)
+	*sk = MQATSecretKey{}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// wipeRounds wipes the randomness and the shares of the rounds, the
+// signature holding its own copies of what it reveals.
+func wipeRounds(rounds []*mqdssRound) {
+	for _, r := range rounds {
+		if r != nil {
+			Wipe(r.r0, r.r1, r.t0, r.t1, r.e0, r.e1, r.fr0)
+		}
+	}
+}
+
+func (t *seedTree) wipe() {
+	for _, node := range t.nodes {
+		Wipe(node)
+	}
+}
+
+func (key *nistUOVKey) wipe() {
+	Wipe(key.seed_sk, key.O, key.S)
+}
@@ -0,0 +1,196 @@
+package crypto
+
+import (
+	"bytes"
+	constants "mqat/const"
+	"testing"
+)
+
+// wipeLog records the buffers Wipe clears, with their contents just before.
+type wipeLog struct {
+	bufs, contents [][]uint8
+}
+
+func recordWipes(t *testing.T) *wipeLog {
+	log := new(wipeLog)
+	wipeHook = func(b []uint8) {
+		log.bufs = append(log.bufs, b)
+		log.contents = append(log.contents, bytes.Clone(b))
+	}
+	t.Cleanup(func() { wipeHook = nil })
+	return log
+}
+
+// wiped reports whether a buffer starting with prefix was wiped.
+func (log *wipeLog) wiped(prefix []uint8) bool {
+	for _, c := range log.contents {
+		if len(prefix) > 0 && bytes.HasPrefix(c, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// zero reports whether the wiped buffers are still zero, i.e. none was
+// written again after being wiped.
+func (log *wipeLog) zero() bool {
+	return isZero(log.bufs...)
+}
+
+func isZero(bufs ...[]uint8) bool {
+	for _, b := range bufs {
+		for _, a := range b {
+			if a != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestUOVSignWipes(t *testing.T) {
+	uov := NewUOV(16, 48, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, Insecure())
+	sk, pk := uov.KeyGen()
+	msg := Nrand128(uov.M, []byte{1})
+	log := recordWipes(t)
+	sig := uov.Sign(msg, sk)
+	if sig == nil || !uov.Verify(msg, sig, pk) {
+		t.Fatal("signature does not verify")
+	}
+	// the vinegar of the first attempt and the oil part x of the signature
+	v := uov.N - uov.M
+	if !log.wiped(domUOVVinegar.sample(uov.Field, v, msg, sk.Seed, []byte{0})) {
+		t.Error("vinegar not wiped")
+	}
+	if !log.wiped(sig[v:]) {
+		t.Error("oil solution not wiped")
+	}
+	if !log.zero() {
+		t.Error("a wiped temporary was written again")
+	}
+}
+
+func TestWipeRounds(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		opts := []Option{Insecure()}
+		if compact {
+			opts = append(opts, Compact())
+		}
+		mqdss := NewMQDSS(16, 32, 16, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, opts...)
+		sk, pk := mqdss.KeyGen()
+		N, M := mqdss.N, mqdss.M
+
+		// r0, t0 and e0 of the rounds partition the buffer they are
+		// sampled in
+		rounds := mqdss.prepare(pk, []byte{1}, []byte{2})
+		for _, r := range rounds {
+			mqdss.commit(sk, r)
+		}
+		r0t0e0 := rounds[0].r0[:cap(rounds[0].r0)]
+		wipeRounds(rounds)
+		if len(r0t0e0) != (2*N+M)*mqdss.R || !isZero(r0t0e0) {
+			t.Errorf("compact %v: r0t0e0 not wiped", compact)
+		}
+		for i, r := range rounds {
+			if !isZero(r.r0, r.r1, r.t0, r.t1, r.e0, r.e1, r.fr0) {
+				t.Errorf("compact %v: round %d not wiped", compact, i)
+			}
+		}
+
+		// the buffers sampled while signing, per round in the compact
+		// format
+		log := recordWipes(t)
+		msg := []byte{3}
+		if !mqdss.Verify(msg, mqdss.Sign(msg, sk), pk) {
+			t.Fatalf("compact %v: signature does not verify", compact)
+		}
+		sampled := (2*N + M) * mqdss.R
+		if compact {
+			sampled = 2*N + M
+		}
+		found := false
+		for _, b := range log.bufs {
+			if cap(b) == sampled && isZero(b[:cap(b)]) {
+				found = true
+			}
+		}
+		if !found || !log.zero() {
+			t.Errorf("compact %v: round randomness not wiped", compact)
+		}
+	}
+}
+
+func TestIDProverWipes(t *testing.T) {
+	mqdss := NewMQDSS(16, 32, 16, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, Interactive(), Insecure())
+	sk, pk := mqdss.KeyGen()
+	for _, malformed := range []bool{false, true} {
+		p, v := mqdss.NewProver(sk), mqdss.NewVerifier(pk)
+		commitment, _ := p.Commit()
+		rounds := p.rounds
+		alphas, _ := v.ChallengeAlphas(commitment)
+		response, _ := p.Respond(alphas)
+		bits, _ := v.ChallengeBits(response)
+		if malformed {
+			bits = bits[1:]
+		}
+		opening, err := p.Open(bits)
+		if malformed != (err == ErrMalformedMessage) || !malformed && !v.Verify(opening) {
+			t.Fatalf("malformed %v: opening %v", malformed, err)
+		}
+		for i, r := range rounds {
+			if !isZero(r.r0, r.r1, r.t0, r.t1, r.e0, r.e1, r.fr0) {
+				t.Errorf("malformed %v: round %d not wiped", malformed, i)
+			}
+		}
+		if _, err := p.Open(bits); err != ErrProtocolState {
+			t.Errorf("malformed %v: opened twice: %v", malformed, err)
+		}
+	}
+}
+
+func TestNISTUOVKeyWipe(t *testing.T) {
+	uov := UOV_Is.New(UOVPkcSkc)
+	sk, _, err := uov.KeyGen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := uov.secretKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed_sk, O, S := key.seed_sk, key.O, key.S
+	key.wipe()
+	if !isZero(seed_sk, O, S) {
+		t.Error("nistUOVKey.wipe left secret fields")
+	}
+
+	key, _ = uov.secretKey(sk)
+	log := recordWipes(t)
+	target := domUOVMessage.sample(uov.Field, uov.M, []byte{4})
+	if uov.SignTarget(target, sk) == nil {
+		t.Fatal("no signature")
+	}
+	if !log.wiped(key.O) || !log.wiped(key.S) || !log.wiped(key.seed_sk) || !log.zero() {
+		t.Error("expanded key not wiped after signing")
+	}
+}
+
+func TestMAYOSignWipes(t *testing.T) {
+	mayo := NewMAYO(16, 20, 4, 5, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, Insecure())
+	sk, pk := mayo.KeyGen()
+	target := Nrand128(mayo.M, []byte{5})
+	log := recordWipes(t)
+	sig := mayo.Sign(target, sk)
+	if sig == nil || !mayo.Verify(target, sig, pk) {
+		t.Fatal("signature does not verify")
+	}
+	// rnd keeps its vinegar part, the oil part being solved in place
+	k, v, o := mayo.K, mayo.N-mayo.O, mayo.O
+	rnd := domMAYOVinegar.sample(mayo.Field, k*v+k*o, target, sk.Seed, []byte{0})
+	if !log.wiped(rnd[:k*v]) {
+		t.Error("MAYO randomness not wiped")
+	}
+	if !log.zero() {
+		t.Error("a wiped temporary was written again")
+	}
+}
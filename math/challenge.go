@@ -0,0 +1,253 @@
+package math
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrChallengeFormat = errors.New("math: malformed MQ Challenge file")
+
+// Challenge is an instance in the MQ Challenge text format: m quadratic
+// polynomials in n variables with linear and constant terms, to be solved for
+// zero. Since MQSystem is homogeneous, an instance with linear or constant
+// terms is homogenized with an extra last variable x_0: the linear terms
+// become x_i x_0 and the constant becomes x_0^2, so solutions of the
+// challenge are the zeros of System with x_0 = 1.
+type Challenge struct {
+	System      *MQSystem
+	Seed        int64
+	Homogenized bool
+}
+
+// NewChallenge returns the instance F(x) = target. A nil target gives the
+// homogeneous instance F(x) = 0.
+func NewChallenge(F *MQSystem, target []uint8) (*Challenge, error) {
+	if target == nil {
+		return &Challenge{System: F}, nil
+	}
+	if len(target) != F.M {
+		return nil, ErrDimensions
+	}
+	c := make([]uint8, F.M)
+	for k := range c {
+		c[k] = F.Field.Neg(target[k])
+	}
+	x0, err := NewMQSystem(F.Field, F.M, 1, c)
+	if err != nil {
+		return nil, err
+	}
+	sys, err := F.Concat(x0)
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{System: sys, Homogenized: true}, nil
+}
+
+// Variables returns the number of variables of the challenge, not counting
+// the homogenizing variable.
+func (c *Challenge) Variables() int {
+	if c.Homogenized {
+		return c.System.N - 1
+	}
+	return c.System.N
+}
+
+func ReadChallenge(r io.Reader) (*Challenge, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 1<<16), 1<<26)
+
+	var F Field
+	n, m := -1, -1
+	var seed int64
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "*") {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		var err error
+		switch {
+		case strings.HasPrefix(key, "galois field"):
+			F, err = parseChallengeField(value)
+		case strings.HasPrefix(key, "number of variables"):
+			n, err = strconv.Atoi(value)
+		case strings.HasPrefix(key, "number of polynomials"):
+			m, err = strconv.Atoi(value)
+		case strings.HasPrefix(key, "seed"):
+			seed, err = strconv.ParseInt(value, 10, 64)
+		case strings.HasPrefix(key, "order"):
+			if !strings.Contains(value, "graded reverse lex") {
+				err = ErrChallengeFormat
+			}
+		}
+		if err != nil {
+			return nil, ErrChallengeFormat
+		}
+	}
+	if F == nil || n <= 0 || m <= 0 {
+		return nil, ErrChallengeFormat
+	}
+
+	quad := n * (n + 1) / 2
+	rows := make([][]uint8, 0, m)
+	row := make([]uint8, 0, quad+n+1)
+	for sc.Scan() && len(rows) < m {
+		for _, tok := range strings.Fields(sc.Text()) {
+			end := strings.HasSuffix(tok, ";")
+			tok = strings.TrimSuffix(tok, ";")
+			if tok != "" {
+				v, err := strconv.Atoi(tok)
+				if err != nil || v < 0 || v >= F.Order() {
+					return nil, ErrChallengeFormat
+				}
+				row = append(row, uint8(v))
+			}
+			if end {
+				if len(row) != quad+n+1 {
+					return nil, ErrChallengeFormat
+				}
+				rows = append(rows, row)
+				row = make([]uint8, 0, quad+n+1)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(rows) != m {
+		return nil, ErrChallengeFormat
+	}
+
+	homogeneous := true
+	for _, row := range rows {
+		for _, v := range row[quad:] {
+			homogeneous = homogeneous && v == 0
+		}
+	}
+	N := n
+	if !homogeneous {
+		N = n + 1
+	}
+	stride := N * (N + 1) / 2
+	coeffs := make([]uint8, m*stride)
+	for k, row := range rows {
+		eq := coeffs[k*stride : (k+1)*stride]
+		pos := 0
+		for j := 0; j < n; j++ {
+			for i := 0; i <= j; i++ {
+				eq[N*i+j-i*(i+1)/2] = row[pos]
+				pos++
+			}
+		}
+		if !homogeneous {
+			for i := 0; i <= n; i++ {
+				eq[N*i+n-i*(i+1)/2] = row[quad+i]
+			}
+		}
+	}
+	sys, err := NewMQSystem(F, m, N, coeffs)
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{System: sys, Seed: seed, Homogenized: !homogeneous}, nil
+}
+
+func WriteChallenge(w io.Writer, c *Challenge) error {
+	sys := c.System
+	name, err := challengeFieldName(sys.Field)
+	if err != nil {
+		return err
+	}
+	n := c.Variables()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Galois Field : %s\n", name)
+	fmt.Fprintf(bw, "Number of variables (n) : %d\n", n)
+	fmt.Fprintf(bw, "Number of polynomials (m) : %d\n", sys.M)
+	fmt.Fprintf(bw, "Seed : %d\n", c.Seed)
+	fmt.Fprintf(bw, "Order : graded reverse lex order\n")
+	fmt.Fprintf(bw, "\n*********************\n")
+	for k := 0; k < sys.M; k++ {
+		for j := 0; j < n; j++ {
+			for i := 0; i <= j; i++ {
+				fmt.Fprintf(bw, "%d ", sys.At(k, i, j))
+			}
+		}
+		for i := 0; i <= n; i++ {
+			var v uint8
+			if c.Homogenized {
+				v = sys.At(k, i, n)
+			}
+			fmt.Fprintf(bw, "%d ", v)
+		}
+		fmt.Fprintf(bw, ";\n")
+	}
+	return bw.Flush()
+}
+
+// MarshalBinary encodes the challenge as a flag byte (1 if homogenized), the
+// seed (8 bytes, big endian) and the binary encoding of the system.
+func (c *Challenge) MarshalBinary() ([]byte, error) {
+	sys, err := c.System.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 9, 9+len(sys))
+	if c.Homogenized {
+		out[0] = 1
+	}
+	binary.BigEndian.PutUint64(out[1:9], uint64(c.Seed))
+	return append(out, sys...), nil
+}
+
+func (c *Challenge) UnmarshalBinary(data []byte) error {
+	if len(data) < 9 || data[0] > 1 {
+		return ErrEncoding
+	}
+	sys := new(MQSystem)
+	if err := sys.UnmarshalBinary(data[9:]); err != nil {
+		return err
+	}
+	c.System = sys
+	c.Homogenized = data[0] == 1
+	c.Seed = int64(binary.BigEndian.Uint64(data[1:9]))
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+func parseChallengeField(s string) (Field, error) {
+	switch strings.ReplaceAll(s, " ", "") {
+	case "GF(16)", "GF(2)[x]/x^4+x+1":
+		return GF16, nil
+	case "GF(31)":
+		return GF31, nil
+	case "GF(256)", "GF(2)[x]/x^8+x^4+x^3+x+1":
+		return GF256, nil
+	}
+	return nil, ErrChallengeFormat
+}
+
+func challengeFieldName(F Field) (string, error) {
+	switch F {
+	case GF16:
+		return "GF(2)[x]/x^4+x+1", nil
+	case GF31:
+		return "GF(31)", nil
+	case GF256:
+		return "GF(2)[x]/x^8+x^4+x^3+x+1", nil
+	}
+	return "", ErrChallengeFormat
+}
@@ -0,0 +1,212 @@
+package math
+
+import (
+	"io"
+)
+
+// Extension is GF(q^K) over a base field of order q. An element is the
+// vector of its K coefficients over the base field, lowest degree first, and
+// vectors of extension elements are the concatenation of their elements, so
+// that packing and sampling are those of the base field.
+type Extension struct {
+	Base Field
+	K    int
+	// Modulus holds the coefficients below x^K of the monic irreducible
+	// polynomial defining the extension.
+	Modulus []uint8
+}
+
+// NewExtension returns GF(q^k) over F, defined by the first monic
+// irreducible polynomial of degree k when ordering their coefficients from
+// the constant term up. The order q^k must fit in 62 bits.
+func NewExtension(F Field, k int) (*Extension, error) {
+	q := F.Order()
+	if k < 1 {
+		return nil, ErrDimensions
+	}
+	for i, order := 0, 1; i < k; i++ {
+		if order > (1<<62)/q {
+			return nil, ErrDimensions
+		}
+		order *= q
+	}
+	E := &Extension{Base: F, K: k, Modulus: make([]uint8, k)}
+	if k == 1 {
+		return E, nil
+	}
+	for idx := 1; ; idx++ {
+		for i, v := 0, idx; i < k; i, v = i+1, v/q {
+			E.Modulus[i] = uint8(v % q)
+		}
+		if E.irreducible() {
+			return E, nil
+		}
+	}
+}
+
+func (E *Extension) Order() int {
+	q := E.Base.Order()
+	res := 1
+	for i := 0; i < E.K; i++ {
+		res *= q
+	}
+	return res
+}
+
+func (E *Extension) Embed(a uint8) []uint8 {
+	res := make([]uint8, E.K)
+	res[0] = a
+	return res
+}
+
+func (E *Extension) IsZero(a []uint8) bool {
+	for _, c := range a {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (E *Extension) Add(a, b []uint8) []uint8 {
+	res := make([]uint8, E.K)
+	for i := range res {
+		res[i] = E.Base.Add(a[i], b[i])
+	}
+	return res
+}
+
+func (E *Extension) Sub(a, b []uint8) []uint8 {
+	res := make([]uint8, E.K)
+	for i := range res {
+		res[i] = E.Base.Sub(a[i], b[i])
+	}
+	return res
+}
+
+func (E *Extension) Neg(a []uint8) []uint8 {
+	return E.Sub(make([]uint8, E.K), a)
+}
+
+// MulBase multiplies a by the base field element c.
+func (E *Extension) MulBase(a []uint8, c uint8) []uint8 {
+	res := make([]uint8, E.K)
+	for i := range res {
+		res[i] = E.Base.Mul(a[i], c)
+	}
+	return res
+}
+
+func (E *Extension) Mul(a, b []uint8) []uint8 {
+	return E.reduce(polyMul(E.Base, a, b))
+}
+
+// Inv returns a^(q^K - 2), the inverse of a nonzero a.
+func (E *Extension) Inv(a []uint8) []uint8 {
+	return E.pow(a, E.Order()-2)
+}
+
+func (E *Extension) PackedLen(n int) int {
+	return E.Base.PackedLen(n * E.K)
+}
+
+func (E *Extension) Pack(elems []uint8) []byte {
+	return E.Base.Pack(elems)
+}
+
+func (E *Extension) Unpack(data []byte, n int) []uint8 {
+	return E.Base.Unpack(data, n*E.K)
+}
+
+func (E *Extension) Sample(r io.Reader, n int) []uint8 {
+	return E.Base.Sample(r, n*E.K)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// reduce returns p modulo the modulus of E, padded to K coefficients.
+func (E *Extension) reduce(p []uint8) []uint8 {
+	F := E.Base
+	p = append([]uint8{}, p...)
+	for d := len(p) - 1; d >= E.K; d-- {
+		if c := p[d]; c != 0 {
+			for i := 0; i < E.K; i++ {
+				p[d-E.K+i] = F.Sub(p[d-E.K+i], F.Mul(c, E.Modulus[i]))
+			}
+		}
+	}
+	res := make([]uint8, E.K)
+	copy(res, p)
+	return res
+}
+
+func (E *Extension) pow(a []uint8, e int) []uint8 {
+	res := E.Embed(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			res = E.Mul(res, a)
+		}
+		a = E.Mul(a, a)
+	}
+	return res
+}
+
+// irreducible runs Ben-Or's test on the modulus f: f is irreducible if and
+// only if gcd(f, x^(q^i) - x) = 1 for i = 1, ..., K/2.
+func (E *Extension) irreducible() bool {
+	F := E.Base
+	f := append(append([]uint8{}, E.Modulus...), 1)
+	x := E.reduce([]uint8{0, 1})
+	xq := x
+	for i := 1; i <= E.K/2; i++ {
+		xq = E.pow(xq, F.Order())
+		g := polyGCD(F, f, trim(E.Sub(xq, x)))
+		if len(g) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func polyMul(F Field, a, b []uint8) []uint8 {
+	res := make([]uint8, len(a)+len(b)-1)
+	for i, ai := range a {
+		if ai == 0 {
+			continue
+		}
+		for j, bj := range b {
+			res[i+j] = F.Add(res[i+j], F.Mul(ai, bj))
+		}
+	}
+	return res
+}
+
+// polyGCD returns the gcd of a and b up to a scalar, as a trimmed
+// polynomial; the zero polynomial is empty.
+func polyGCD(F Field, a, b []uint8) []uint8 {
+	a, b = trim(a), trim(b)
+	for len(b) > 0 {
+		// a mod b
+		inv := F.Inv(b[len(b)-1])
+		r := append([]uint8{}, a...)
+		for len(r) >= len(b) {
+			c := F.Mul(r[len(r)-1], inv)
+			shift := len(r) - len(b)
+			for i, bi := range b {
+				r[shift+i] = F.Sub(r[shift+i], F.Mul(c, bi))
+			}
+			r = trim(r)
+		}
+		a, b = b, r
+	}
+	return a
+}
+
+func trim(p []uint8) []uint8 {
+	for len(p) > 0 && p[len(p)-1] == 0 {
+		p = p[:len(p)-1]
+	}
+	return p
+}
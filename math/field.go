@@ -0,0 +1,47 @@
+package math
+
+import (
+	"errors"
+	"io"
+)
+
+// Field is a finite field whose elements fit in a byte. Elements are kept
+// unpacked (one per uint8) for arithmetic; Pack and Unpack convert to and
+// from the storage encoding of the field.
+type Field interface {
+	Order() int
+	Add(a, b uint8) uint8
+	Sub(a, b uint8) uint8
+	Neg(a uint8) uint8
+	Mul(a, b uint8) uint8
+	Inv(a uint8) uint8
+	PackedLen(n int) int
+	Pack(elems []uint8) []byte
+	Unpack(data []byte, n int) []uint8
+	Sample(r io.Reader, n int) []uint8
+}
+
+var (
+	GF16  Field = gf16{}
+	GF31  Field = gf31{}
+	GF256 Field = gf256{}
+)
+
+// charTwo reports whether F has characteristic 2, in which case addition is
+// XOR and hot loops can inline it instead of calling F.Add; multiplication
+// still goes through F.
+func charTwo(F Field) bool {
+	return F == GF256 || F == GF16
+}
+
+func FieldByOrder(q int) (Field, error) {
+	switch q {
+	case 16:
+		return GF16, nil
+	case 31:
+		return GF31, nil
+	case 256:
+		return GF256, nil
+	}
+	return nil, errors.New("math: unsupported field order")
+}
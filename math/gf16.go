@@ -0,0 +1,64 @@
+package math
+
+import (
+	"io"
+)
+
+// GF(16) with modulus x^4 + x + 1, packed two elements per byte with the
+// first element in the low nibble.
+
+func mul16(a, b uint8) uint8 {
+	r := a * (b & 1)
+
+	a = ((a << 1) & 0xf) ^ ((a >> 3) * 0x3)
+	r ^= a * ((b >> 1) & 1)
+	a = ((a << 1) & 0xf) ^ ((a >> 3) * 0x3)
+	r ^= a * ((b >> 2) & 1)
+	a = ((a << 1) & 0xf) ^ ((a >> 3) * 0x3)
+	r ^= a * ((b >> 3) & 1)
+	return r
+}
+
+func inv16(a uint8) uint8 {
+	a2 := mul16(a, a)
+	a4 := mul16(a2, a2)
+	a8 := mul16(a4, a4)
+	return mul16(mul16(a8, a4), a2)
+}
+
+type gf16 struct{}
+
+func (gf16) Order() int           { return 16 }
+func (gf16) Add(a, b uint8) uint8 { return a ^ b }
+func (gf16) Sub(a, b uint8) uint8 { return a ^ b }
+func (gf16) Neg(a uint8) uint8    { return a }
+func (gf16) Mul(a, b uint8) uint8 { return mul16(a, b) }
+func (gf16) Inv(a uint8) uint8    { return inv16(a) }
+func (gf16) PackedLen(n int) int  { return (n + 1) / 2 }
+
+func (gf16) Pack(elems []uint8) []byte {
+	out := make([]byte, (len(elems)+1)/2)
+	for i, e := range elems {
+		out[i/2] |= (e & 0xf) << (4 * (i & 1))
+	}
+	return out
+}
+
+func (gf16) Unpack(data []byte, n int) []uint8 {
+	if len(data) < (n+1)/2 {
+		return nil
+	}
+	out := make([]uint8, n)
+	for i := 0; i < n; i++ {
+		out[i] = (data[i/2] >> (4 * (i & 1))) & 0xf
+	}
+	return out
+}
+
+func (f gf16) Sample(r io.Reader, n int) []uint8 {
+	buf := make([]byte, f.PackedLen(n))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil
+	}
+	return f.Unpack(buf, n)
+}
@@ -1,5 +1,10 @@
 package math
 
+import (
+	"bytes"
+	"io"
+)
+
 func IsNonZero(a uint8) bool {
 	a8 := uint(a)
 	r := 0 - a8
@@ -54,3 +59,31 @@ func Inv(a uint8) uint8 {
 	a128_ := Square(a64_2)
 	return Mul(a2, a128_)
 }
+
+////////////////////////////////////////////////////////////////////////////////
+
+type gf256 struct{}
+
+func (gf256) Order() int                { return 256 }
+func (gf256) Add(a, b uint8) uint8      { return a ^ b }
+func (gf256) Sub(a, b uint8) uint8      { return a ^ b }
+func (gf256) Neg(a uint8) uint8         { return a }
+func (gf256) Mul(a, b uint8) uint8      { return Mul(a, b) }
+func (gf256) Inv(a uint8) uint8         { return Inv(a) }
+func (gf256) PackedLen(n int) int       { return n }
+func (gf256) Pack(elems []uint8) []byte { return bytes.Clone(elems) }
+
+func (gf256) Unpack(data []byte, n int) []uint8 {
+	if len(data) < n {
+		return nil
+	}
+	return bytes.Clone(data[:n])
+}
+
+func (gf256) Sample(r io.Reader, n int) []uint8 {
+	out := make([]uint8, n)
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package math
+
+import (
+	"bytes"
+	"io"
+)
+
+// GF(31) as used by the original MQDSS specification. Elements are stored
+// one per byte in [0, 31).
+
+type gf31 struct{}
+
+func (gf31) Order() int           { return 31 }
+func (gf31) Add(a, b uint8) uint8 { return uint8((uint16(a) + uint16(b)) % 31) }
+func (gf31) Sub(a, b uint8) uint8 { return uint8((uint16(a) + 31 - uint16(b)) % 31) }
+func (gf31) Neg(a uint8) uint8    { return uint8((31 - uint16(a)) % 31) }
+func (gf31) Mul(a, b uint8) uint8 { return uint8((uint16(a) * uint16(b)) % 31) }
+
+func (f gf31) Inv(a uint8) uint8 {
+	// a^29 = a^-1 by Fermat
+	r := uint8(1)
+	for e := 29; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = f.Mul(r, a)
+		}
+		a = f.Mul(a, a)
+	}
+	return r
+}
+
+func (gf31) PackedLen(n int) int       { return n }
+func (gf31) Pack(elems []uint8) []byte { return bytes.Clone(elems) }
+
+func (gf31) Unpack(data []byte, n int) []uint8 {
+	if len(data) < n {
+		return nil
+	}
+	out := bytes.Clone(data[:n])
+	for _, v := range out {
+		if v >= 31 {
+			return nil
+		}
+	}
+	return out
+}
+
+func (gf31) Sample(r io.Reader, n int) []uint8 {
+	out := make([]uint8, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil
+		}
+		for _, b := range buf {
+			// rejection sampling on 5 bits keeps the output uniform
+			if v := b & 0x1f; v < 31 && len(out) < n {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
@@ -125,6 +125,22 @@ func (v Vector) Set(i, j int, val uint8) {
 ////////////////////////////////////////////////////////////////////////////////
 
 func MulMat(A, B Matrix) *Dense {
+	return MulMatOver(GF256, A, B)
+}
+
+func AddMat(A, B Matrix) *Dense {
+	return AddMatOver(GF256, A, B)
+}
+
+func ScaleMat(M Matrix, v uint8) *Dense {
+	return ScaleMatOver(GF256, M, v)
+}
+
+func Solve(A Matrix, b Vector) Vector {
+	return SolveOver(GF256, A, b)
+}
+
+func MulMatOver(F Field, A, B Matrix) *Dense {
 	rowsA, colsA := A.Dims()
 	rowsB, colsB := B.Dims()
 	if colsA != rowsB {
@@ -132,11 +148,21 @@ func MulMat(A, B Matrix) *Dense {
 	}
 
 	res := NewDenseMatrix(rowsA, colsB, nil)
+	// GF256 calls the constant-time Mul directly, skipping the
+	// interface call
+	isGF256 := F == GF256
+	xor := charTwo(F)
 	for i := 0; i < rowsA; i++ {
 		for j := 0; j < colsB; j++ {
 			var tmp uint8 = 0
 			for k := 0; k < colsA; k++ {
-				tmp ^= Mul(A.At(i, k), B.At(k, j))
+				if isGF256 {
+					tmp ^= Mul(A.At(i, k), B.At(k, j))
+				} else if xor {
+					tmp ^= F.Mul(A.At(i, k), B.At(k, j))
+				} else {
+					tmp = F.Add(tmp, F.Mul(A.At(i, k), B.At(k, j)))
+				}
 			}
 			res.Set(i, j, tmp)
 		}
@@ -145,7 +171,7 @@ func MulMat(A, B Matrix) *Dense {
 	return res
 }
 
-func AddMat(A, B Matrix) *Dense {
+func AddMatOver(F Field, A, B Matrix) *Dense {
 	rowsA, colsA := A.Dims()
 	rowsB, colsB := B.Dims()
 	if rowsA != rowsB || colsA != colsB {
@@ -155,26 +181,43 @@ func AddMat(A, B Matrix) *Dense {
 	res := NewDenseMatrix(rowsA, colsB, nil)
 	for i := 0; i < rowsA; i++ {
 		for j := 0; j < colsB; j++ {
-			res.Set(i, j, A.At(i, j)^B.At(i, j))
+			res.Set(i, j, F.Add(A.At(i, j), B.At(i, j)))
 		}
 	}
 
 	return res
 }
 
-func ScaleMat(M Matrix, v uint8) *Dense {
+func SubMatOver(F Field, A, B Matrix) *Dense {
+	rowsA, colsA := A.Dims()
+	rowsB, colsB := B.Dims()
+	if rowsA != rowsB || colsA != colsB {
+		return nil
+	}
+
+	res := NewDenseMatrix(rowsA, colsB, nil)
+	for i := 0; i < rowsA; i++ {
+		for j := 0; j < colsB; j++ {
+			res.Set(i, j, F.Sub(A.At(i, j), B.At(i, j)))
+		}
+	}
+
+	return res
+}
+
+func ScaleMatOver(F Field, M Matrix, v uint8) *Dense {
 	rows, cols := M.Dims()
 	res := NewDenseMatrix(rows, cols, nil)
 	for i := 0; i < rows; i++ {
 		for j := 0; j < cols; j++ {
-			val := Mul(v, M.At(i, j))
+			val := F.Mul(v, M.At(i, j))
 			res.Set(i, j, val)
 		}
 	}
 	return res
 }
 
-func Solve(A Matrix, b Vector) Vector {
+func SolveOver(F Field, A Matrix, b Vector) Vector {
 	r, c := A.Dims()
 	l, _ := b.Dims()
 	if r != c || r != l {
@@ -197,21 +240,21 @@ func Solve(A Matrix, b Vector) Vector {
 		for j := i + 1; j < l; j++ {
 			if AbMat.At(i, i) == 0 {
 				for k := i; k < l+1; k++ {
-					AbMat.Set(i, k, AbMat.At(i, k)^AbMat.At(j, k))
+					AbMat.Set(i, k, F.Add(AbMat.At(i, k), AbMat.At(j, k)))
 				}
 			}
 		}
 		if AbMat.At(i, i) == 0 {
 			return Vector{}
 		}
-		pi := Inv(AbMat.At(i, i))
+		pi := F.Inv(AbMat.At(i, i))
 		for k := i; k < l+1; k++ {
-			AbMat.Set(i, k, Mul(pi, AbMat.At(i, k)))
+			AbMat.Set(i, k, F.Mul(pi, AbMat.At(i, k)))
 		}
 		for j := i + 1; j < l; j++ {
 			aji := AbMat.At(j, i)
 			for k := i; k < l+1; k++ {
-				AbMat.Set(j, k, AbMat.At(j, k)^Mul(aji, AbMat.At(i, k)))
+				AbMat.Set(j, k, F.Sub(AbMat.At(j, k), F.Mul(aji, AbMat.At(i, k))))
 			}
 		}
 	}
@@ -219,7 +262,7 @@ func Solve(A Matrix, b Vector) Vector {
 	for i := l - 1; i > 0; i-- {
 		aim := AbMat.At(i, l)
 		for j := 0; j < i; j++ {
-			AbMat.Set(j, l, AbMat.At(j, l)^Mul(AbMat.At(j, i), aim))
+			AbMat.Set(j, l, F.Sub(AbMat.At(j, l), F.Mul(AbMat.At(j, i), aim)))
 		}
 	}
 
@@ -230,3 +273,101 @@ func Solve(A Matrix, b Vector) Vector {
 	}
 	return NewVector(res)
 }
+
+// RowReduceOver brings A in place to reduced row echelon form and returns
+// its rank and the pivot column of each of the first rank rows.
+func RowReduceOver(F Field, A *Dense) (int, []int) {
+	rows, cols := A.Dims()
+	pivots := make([]int, 0)
+	r := 0
+	for c := 0; c < cols && r < rows; c++ {
+		p := r
+		for p < rows && A.At(p, c) == 0 {
+			p++
+		}
+		if p == rows {
+			continue
+		}
+		if p != r {
+			for k := c; k < cols; k++ {
+				a, b := A.At(r, k), A.At(p, k)
+				A.Set(r, k, b)
+				A.Set(p, k, a)
+			}
+		}
+		pi := F.Inv(A.At(r, c))
+		for k := c; k < cols; k++ {
+			A.Set(r, k, F.Mul(pi, A.At(r, k)))
+		}
+		for j := 0; j < rows; j++ {
+			ajc := A.At(j, c)
+			if j == r || ajc == 0 {
+				continue
+			}
+			for k := c; k < cols; k++ {
+				A.Set(j, k, F.Sub(A.At(j, k), F.Mul(ajc, A.At(r, k))))
+			}
+		}
+		pivots = append(pivots, c)
+		r++
+	}
+	return r, pivots
+}
+
+// KernelOver returns a basis of the right kernel of A as the columns of an
+// n x d matrix, with d = 0 when A is injective.
+func KernelOver(F Field, A Matrix) *Dense {
+	rows, cols := A.Dims()
+	R := NewDenseMatrix(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			R.Set(i, j, A.At(i, j))
+		}
+	}
+	rank, pivots := RowReduceOver(F, R)
+	isPivot := make([]bool, cols)
+	for _, p := range pivots {
+		isPivot[p] = true
+	}
+
+	K := NewDenseMatrix(cols, cols-rank, nil)
+	d := 0
+	for f := 0; f < cols; f++ {
+		if isPivot[f] {
+			continue
+		}
+		K.Set(f, d, 1)
+		for i, p := range pivots {
+			K.Set(p, d, F.Neg(R.At(i, f)))
+		}
+		d++
+	}
+	return K
+}
+
+// InverseOver returns the inverse of the square matrix A, or nil if A is
+// singular.
+func InverseOver(F Field, A Matrix) *Dense {
+	n, c := A.Dims()
+	if n != c {
+		return nil
+	}
+	AI := NewDenseMatrix(n, 2*n, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			AI.Set(i, j, A.At(i, j))
+		}
+		AI.Set(i, n+i, 1)
+	}
+	rank, pivots := RowReduceOver(F, AI)
+	if rank < n || pivots[n-1] != n-1 {
+		return nil
+	}
+	inv := NewDenseMatrix(n, n, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			inv.Set(i, j, AI.At(i, n+j))
+		}
+	}
+	return inv
+}
@@ -1,47 +1,64 @@
 package math
 
-import constants "mqat/const"
-
 func MQ(P1i, P2i, P3i, R, x []uint8, m, n int) []uint8 {
+	return MQOver(GF256, P1i, P2i, P3i, R, x, m, n)
+}
+
+func MQR(R []uint8, x []uint8, m int) []uint8 {
+	return MQROver(GF256, R, x, m)
+}
+
+func MQP(P1i, P2i, P3i, x []uint8, m int) []uint8 {
+	return MQPOver(GF256, P1i, P2i, P3i, x, m)
+}
+
+func G(P1i, P2i, P3i, R, x, y []uint8, m, n int) []uint8 {
+	return GOver(GF256, P1i, P2i, P3i, R, x, y, m, n)
+}
+
+func MQOver(F Field, P1i, P2i, P3i, R, x []uint8, m, n int) []uint8 {
 	x1 := x[:n]
 	x2 := x[n:]
 
-	Px1 := MQP(P1i, P2i, P3i, x1, m)
-	Rx2 := MQR(R, x2, m)
+	Px1 := MQPOver(F, P1i, P2i, P3i, x1, m)
+	Rx2 := MQROver(F, R, x2, m)
 
 	res := make([]uint8, m)
 	for i := 0; i < m; i++ {
-		res[i] = Px1[i] ^ Rx2[i]
+		res[i] = F.Add(Px1[i], Rx2[i])
 	}
 	return res
 }
 
-func MQR(R []uint8, x []uint8, m int) []uint8 {
-	h_prime := make([]uint8, constants.Q*m)
+func MQROver(F Field, R []uint8, x []uint8, m int) []uint8 {
+	q := F.Order()
+	h_prime := make([]uint8, q*m)
 	n := len(x)
+	xor := charTwo(F)
 	for i := 0; i < n; i++ {
 		for j := i; j < n; j++ {
-			t := Mul(x[i], x[j])
+			t := F.Mul(x[i], x[j])
 			for k := 0; k < m; k++ {
-				h_prime[int(t)*m+k] ^= R[Flen(k, n)+n*i+j-i*(i-1)/2-i]
+				c := R[Flen(k, n)+n*i+j-i*(i-1)/2-i]
+				if xor {
+					h_prime[int(t)*m+k] ^= c
+				} else {
+					h_prime[int(t)*m+k] = F.Add(h_prime[int(t)*m+k], c)
+				}
 			}
 		}
 	}
 
-	h := h_prime[m : 2*m]
-	for t := 2; t < constants.Q; t++ {
-		for k := 0; k < m; k++ {
-			h[k] ^= Mul(uint8(t), h_prime[int(t)*m+k])
-		}
-	}
-	return h
+	return collapse(F, h_prime, m)
 }
 
-func MQP(P1i, P2i, P3i, x []uint8, m int) []uint8 {
-	h_prime := make([]uint8, constants.Q*m)
+func MQPOver(F Field, P1i, P2i, P3i, x []uint8, m int) []uint8 {
+	q := F.Order()
+	h_prime := make([]uint8, q*m)
 	n := len(x)
 
 	vec := NewVector(x)
+	xor := charTwo(F)
 
 	lenP1 := (n - m) * (n - m + 1) / 2
 	lenP2 := (n - m) * m
@@ -75,44 +92,44 @@ func MQP(P1i, P2i, P3i, x []uint8, m int) []uint8 {
 
 	for i := 0; i < n; i++ {
 		for j := i; j < n; j++ {
-			t := Mul(vec.At(i, 0), vec.At(j, 0))
+			t := int(F.Mul(vec.At(i, 0), vec.At(j, 0)))
 			for k := 0; k < m; k++ {
+				var c uint8
 				if j < n-m {
-					h_prime[int(t)*m+k] ^= P1s[k].At(i, j)
+					c = P1s[k].At(i, j)
 				} else {
 					if i < n-m {
-						h_prime[int(t)*m+k] ^= P2s[k].At(i, j-n+m)
+						c = P2s[k].At(i, j-n+m)
 					} else {
-						h_prime[int(t)*m+k] ^= P3s[k].At(i-n+m, j-n+m)
+						c = P3s[k].At(i-n+m, j-n+m)
 					}
 				}
+				if xor {
+					h_prime[t*m+k] ^= c
+				} else {
+					h_prime[t*m+k] = F.Add(h_prime[t*m+k], c)
+				}
 			}
 		}
 	}
 
-	h := h_prime[m : 2*m]
-	for t := 2; t < constants.Q; t++ {
-		for k := 0; k < m; k++ {
-			h[k] ^= Mul(uint8(t), h_prime[int(t)*m+k])
-		}
-	}
-	return h
+	return collapse(F, h_prime, m)
 }
 
-func G(P1i, P2i, P3i, R, x, y []uint8, m, n int) []uint8 {
+func GOver(F Field, P1i, P2i, P3i, R, x, y []uint8, m, n int) []uint8 {
 	if len(x) != len(y) {
 		return nil
 	}
 	gx := make([]uint8, m)
-	fx := MQ(P1i, P2i, P3i, R, x, m, n)
-	fy := MQ(P1i, P2i, P3i, R, y, m, n)
+	fx := MQOver(F, P1i, P2i, P3i, R, x, m, n)
+	fy := MQOver(F, P1i, P2i, P3i, R, y, m, n)
 	xy := make([]uint8, m+n)
 	for i := 0; i < m+n; i++ {
-		xy[i] = x[i] ^ y[i]
+		xy[i] = F.Add(x[i], y[i])
 	}
-	fxy := MQ(P1i, P2i, P3i, R, xy, m, n)
+	fxy := MQOver(F, P1i, P2i, P3i, R, xy, m, n)
 	for i := 0; i < m; i++ {
-		gxi := fxy[i] ^ fx[i] ^ fy[i]
+		gxi := F.Sub(F.Sub(fxy[i], fx[i]), fy[i])
 		gx[i] = gxi
 	}
 	return gx
@@ -121,3 +138,115 @@ func G(P1i, P2i, P3i, R, x, y []uint8, m, n int) []uint8 {
 func Flen(m, n int) int {
 	return m * n * (n + 1) / 2
 }
+
+// The checked evaluators validate every length against the declared
+// dimensions and every entry against F before evaluating, returning
+// ErrDimensions or ErrCoefficients where the unchecked versions above would
+// panic or read the wrong coefficients. They are meant for keys and vectors
+// from outside, e.g. parsed from a file; the schemes call the unchecked ones
+// on values they built.
+
+// MQPChecked is MQPOver for the UOV blocks of m equations in len(x)
+// variables.
+func MQPChecked(F Field, P1i, P2i, P3i, x []uint8, m int) ([]uint8, error) {
+	if err := checkMQP(F, P1i, P2i, P3i, m, len(x)); err != nil {
+		return nil, err
+	}
+	if !inRange(F, x) {
+		return nil, ErrCoefficients
+	}
+	return MQPOver(F, P1i, P2i, P3i, x, m), nil
+}
+
+// MQRChecked is MQROver for m equations in len(x) variables.
+func MQRChecked(F Field, R, x []uint8, m int) ([]uint8, error) {
+	if m <= 0 || len(x) == 0 || len(R) != Flen(m, len(x)) {
+		return nil, ErrDimensions
+	}
+	if !inRange(F, R, x) {
+		return nil, ErrCoefficients
+	}
+	return MQROver(F, R, x, m), nil
+}
+
+// MQChecked is MQOver: P over the first n variables of x and R over the
+// others.
+func MQChecked(F Field, P1i, P2i, P3i, R, x []uint8, m, n int) ([]uint8, error) {
+	if err := checkMQ(F, P1i, P2i, P3i, R, x, m, n); err != nil {
+		return nil, err
+	}
+	return MQOver(F, P1i, P2i, P3i, R, x, m, n), nil
+}
+
+// GChecked is GOver, the polar form of MQChecked. GOver adds x and y over
+// n+m variables, so R must be over m of them.
+func GChecked(F Field, P1i, P2i, P3i, R, x, y []uint8, m, n int) ([]uint8, error) {
+	if len(x) != n+m || len(y) != n+m {
+		return nil, ErrDimensions
+	}
+	if err := checkMQ(F, P1i, P2i, P3i, R, x, m, n); err != nil {
+		return nil, err
+	}
+	if !inRange(F, y) {
+		return nil, ErrCoefficients
+	}
+	return GOver(F, P1i, P2i, P3i, R, x, y, m, n), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// checkMQ checks the inputs of MQOver.
+func checkMQ(F Field, P1i, P2i, P3i, R, x []uint8, m, n int) error {
+	if n <= 0 || len(x) <= n || len(R) != Flen(m, len(x)-n) {
+		return ErrDimensions
+	}
+	if err := checkMQP(F, P1i, P2i, P3i, m, n); err != nil {
+		return err
+	}
+	if !inRange(F, R, x) {
+		return ErrCoefficients
+	}
+	return nil
+}
+
+// checkMQP checks the UOV blocks of m equations in n variables.
+func checkMQP(F Field, P1i, P2i, P3i []uint8, m, n int) error {
+	if m <= 0 || n < m {
+		return ErrDimensions
+	}
+	v := n - m
+	if len(P1i) != m*v*(v+1)/2 || len(P2i) != m*v*m || len(P3i) != m*m*(m+1)/2 {
+		return ErrDimensions
+	}
+	if !inRange(F, P1i, P2i, P3i) {
+		return ErrCoefficients
+	}
+	return nil
+}
+
+// inRange reports whether all the entries are elements of F.
+func inRange(F Field, entries ...[]uint8) bool {
+	q := F.Order()
+	for _, e := range entries {
+		for _, a := range e {
+			if int(a) >= q {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// collapse folds the per-monomial-value buckets h_prime[t*m:(t+1)*m] into
+// sum_t t * h_prime[t], so that each coefficient is multiplied only once.
+func collapse(F Field, h_prime []uint8, m int) []uint8 {
+	h := h_prime[m : 2*m]
+	for t := 2; t < F.Order(); t++ {
+		for k := 0; k < m; k++ {
+			h[k] = F.Add(h[k], F.Mul(uint8(t), h_prime[t*m+k]))
+		}
+	}
+	return h
+}
@@ -0,0 +1,293 @@
+package math
+
+import (
+	"errors"
+	"sort"
+)
+
+// Solvers for toy instances, meant for cryptanalysis experiments only: both
+// run in time exponential in the number of variables.
+
+var (
+	ErrNoSolution = errors.New("math: system has no solution")
+	ErrUnresolved = errors.New("math: degree too low to resolve the system")
+)
+
+// ExhaustiveSearch returns up to limit solutions of c, or all of them if
+// limit is 0, by enumerating the q^n assignments of the variables.
+func ExhaustiveSearch(c *Challenge, limit int) [][]uint8 {
+	p := quadraticOf(c)
+	q := p.F.Order()
+	x := make([]uint8, p.n)
+	sols := make([][]uint8, 0)
+	for {
+		if p.isZero(x) {
+			sols = append(sols, append([]uint8{}, x...))
+			if limit > 0 && len(sols) >= limit {
+				return sols
+			}
+		}
+		i := 0
+		for ; i < p.n; i++ {
+			if int(x[i]) < q-1 {
+				x[i]++
+				break
+			}
+			x[i] = 0
+		}
+		if i == p.n {
+			return sols
+		}
+	}
+}
+
+// XL runs the eXtended Linearization algorithm at degree D >= 2: every
+// equation is multiplied by every monomial of degree at most D-2 and the
+// resulting system is row reduced with the monomials of degree at most D
+// taken as independent unknowns. Plain linearization is XL at degree 2. The
+// solution is returned once the reduced system determines every variable.
+func XL(c *Challenge, D int) ([]uint8, error) {
+	if D < 2 {
+		return nil, ErrUnresolved
+	}
+	p := quadraticOf(c)
+	F := p.F
+	n := p.n
+
+	cols := make([]monomial, 0)
+	for d := D; d >= 0; d-- {
+		cols = append(cols, monomials(n, d)...)
+	}
+	index := make(map[string]int, len(cols))
+	for i, u := range cols {
+		index[u.key()] = i
+	}
+	multipliers := make([]monomial, 0)
+	for d := D - 2; d >= 0; d-- {
+		multipliers = append(multipliers, monomials(n, d)...)
+	}
+
+	rows := len(multipliers) * p.m
+	A := NewDenseMatrix(rows, len(cols), nil)
+	r := 0
+	for _, u := range multipliers {
+		for k := 0; k < p.m; k++ {
+			add := func(v monomial, coeff uint8) {
+				j := index[u.times(v).key()]
+				A.Set(r, j, F.Add(A.At(r, j), coeff))
+			}
+			for i := 0; i < n; i++ {
+				for j := i; j < n; j++ {
+					if a := p.quad.At(k, i, j); a != 0 {
+						add(monomial{i, j}, a)
+					}
+				}
+				if a := p.lin[k*n+i]; a != 0 {
+					add(monomial{i}, a)
+				}
+			}
+			if a := p.cst[k]; a != 0 {
+				add(monomial{}, a)
+			}
+			r++
+		}
+	}
+
+	rank, pivots := RowReduceOver(F, A)
+	constant := len(cols) - 1
+	value := make(map[int]uint8)
+	for row := 0; row < rank; row++ {
+		pc := pivots[row]
+		if pc == constant {
+			return nil, ErrNoSolution
+		}
+		determined := true
+		for j := pc + 1; j < constant; j++ {
+			determined = determined && A.At(row, j) == 0
+		}
+		if determined {
+			value[pc] = F.Neg(A.At(row, constant))
+		}
+	}
+
+	x, ok := make([]uint8, n), true
+	for i := 0; i < n && ok; i++ {
+		x[i], ok = value[index[monomial{i}.key()]]
+	}
+	if ok && p.isZero(x) {
+		return x, nil
+	}
+	// Homogeneous instances do not determine the variables themselves (x
+	// and -x are both solutions) but may determine the degree 2 monomials:
+	// recover x_i from a square root of x_i^2, then the others from x_i x_j.
+	for i := 0; i < n; i++ {
+		sq, ok := value[index[monomial{i, i}.key()]]
+		if !ok || sq == 0 {
+			continue
+		}
+		for a := 1; a < F.Order(); a++ {
+			if F.Mul(uint8(a), uint8(a)) != sq {
+				continue
+			}
+			ainv := F.Inv(uint8(a))
+			complete := true
+			for j := 0; j < n && complete; j++ {
+				var xij uint8
+				xij, complete = value[index[monomial{i, j}.times(nil).key()]]
+				x[j] = F.Mul(xij, ainv)
+			}
+			if complete && p.isZero(x) {
+				return x, nil
+			}
+		}
+	}
+	return nil, ErrUnresolved
+}
+
+// Fix substitutes values for the last len(values) variables of c, as in the
+// hybrid approach where some variables are guessed before solving.
+func (c *Challenge) Fix(values []uint8) (*Challenge, error) {
+	p := quadraticOf(c)
+	F := p.F
+	n := p.n - len(values)
+	if n <= 0 {
+		return nil, ErrDimensions
+	}
+	val := func(i int) uint8 { return values[i-n] }
+
+	res := &quadratic{F: F, m: p.m, n: n, lin: make([]uint8, p.m*n), cst: make([]uint8, p.m)}
+	coeffs := make([]uint8, 0, Flen(p.m, n))
+	for k := 0; k < p.m; k++ {
+		for i := 0; i < n; i++ {
+			for j := i; j < n; j++ {
+				coeffs = append(coeffs, p.quad.At(k, i, j))
+			}
+			lin := p.lin[k*p.n+i]
+			for j := n; j < p.n; j++ {
+				lin = F.Add(lin, F.Mul(p.quad.At(k, i, j), val(j)))
+			}
+			res.lin[k*n+i] = lin
+		}
+		cst := p.cst[k]
+		for i := n; i < p.n; i++ {
+			cst = F.Add(cst, F.Mul(p.lin[k*p.n+i], val(i)))
+			for j := i; j < p.n; j++ {
+				cst = F.Add(cst, F.Mul(p.quad.At(k, i, j), F.Mul(val(i), val(j))))
+			}
+		}
+		res.cst[k] = cst
+	}
+	var err error
+	res.quad, err = NewMQSystem(F, p.m, n, coeffs)
+	if err != nil {
+		return nil, err
+	}
+	return res.challenge(c.Seed)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// quadratic is an inhomogeneous quadratic system quad(x) + lin.x + cst.
+type quadratic struct {
+	F    Field
+	m, n int
+	quad *MQSystem
+	lin  []uint8
+	cst  []uint8
+}
+
+func quadraticOf(c *Challenge) *quadratic {
+	sys := c.System
+	F := sys.Field
+	n := c.Variables()
+	p := &quadratic{F: F, m: sys.M, n: n, lin: make([]uint8, sys.M*n), cst: make([]uint8, sys.M)}
+	if !c.Homogenized {
+		p.quad = sys
+		return p
+	}
+	coeffs := make([]uint8, 0, Flen(sys.M, n))
+	for k := 0; k < sys.M; k++ {
+		for i := 0; i < n; i++ {
+			for j := i; j < n; j++ {
+				coeffs = append(coeffs, sys.At(k, i, j))
+			}
+			p.lin[k*n+i] = sys.At(k, i, n)
+		}
+		p.cst[k] = sys.At(k, n, n)
+	}
+	p.quad = &MQSystem{Field: F, M: sys.M, N: n, Coeffs: coeffs}
+	return p
+}
+
+func (p *quadratic) challenge(seed int64) (*Challenge, error) {
+	N := p.n + 1
+	stride := N * (N + 1) / 2
+	coeffs := make([]uint8, p.m*stride)
+	for k := 0; k < p.m; k++ {
+		eq := coeffs[k*stride : (k+1)*stride]
+		for i := 0; i < p.n; i++ {
+			for j := i; j < p.n; j++ {
+				eq[N*i+j-i*(i+1)/2] = p.quad.At(k, i, j)
+			}
+			eq[N*i+p.n-i*(i+1)/2] = p.lin[k*p.n+i]
+		}
+		eq[stride-1] = p.cst[k]
+	}
+	sys, err := NewMQSystem(p.F, p.m, N, coeffs)
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{System: sys, Seed: seed, Homogenized: true}, nil
+}
+
+func (p *quadratic) isZero(x []uint8) bool {
+	F := p.F
+	y := p.quad.Eval(x)
+	for k := 0; k < p.m; k++ {
+		v := F.Add(y[k], p.cst[k])
+		for i := 0; i < p.n; i++ {
+			v = F.Add(v, F.Mul(p.lin[k*p.n+i], x[i]))
+		}
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// monomial is the non-decreasing list of the indices of its variables.
+type monomial []int
+
+func (u monomial) key() string {
+	b := make([]byte, len(u))
+	for i, v := range u {
+		b[i] = byte(v)
+	}
+	return string(b)
+}
+
+func (u monomial) times(v monomial) monomial {
+	w := append(append(monomial{}, u...), v...)
+	sort.Ints(w)
+	return w
+}
+
+// monomials lists the monomials of degree d in n variables.
+func monomials(n, d int) []monomial {
+	if d == 0 {
+		return []monomial{{}}
+	}
+	res := make([]monomial, 0)
+	for _, u := range monomials(n, d-1) {
+		start := 0
+		if len(u) > 0 {
+			start = u[len(u)-1]
+		}
+		for i := start; i < n; i++ {
+			res = append(res, append(append(monomial{}, u...), i))
+		}
+	}
+	return res
+}
@@ -0,0 +1,341 @@
+package math
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	ErrDimensions    = errors.New("math: inconsistent dimensions")
+	ErrCoefficients  = errors.New("math: coefficient out of range")
+	ErrFieldMismatch = errors.New("math: systems are defined over different fields")
+	ErrEncoding      = errors.New("math: malformed encoding")
+)
+
+// MQSystem is a homogeneous quadratic map from F^N to F^M. The coefficients
+// of each equation are stored as the row-major upper triangle of its matrix,
+// equation after equation, i.e. the layout of MQR.
+type MQSystem struct {
+	Field  Field
+	M, N   int
+	Coeffs []uint8
+}
+
+func NewMQSystem(F Field, m, n int, coeffs []uint8) (*MQSystem, error) {
+	if m <= 0 || n <= 0 || len(coeffs) != Flen(m, n) {
+		return nil, ErrDimensions
+	}
+	for _, c := range coeffs {
+		if int(c) >= F.Order() {
+			return nil, ErrCoefficients
+		}
+	}
+	return &MQSystem{Field: F, M: m, N: n, Coeffs: bytes.Clone(coeffs)}, nil
+}
+
+// NewMQSystemFromSeed expands a seed with SHAKE128.
+func NewMQSystemFromSeed(F Field, m, n int, seed []byte) (*MQSystem, error) {
+	xof := sha3.NewShake128()
+	xof.Write(seed)
+	return NewMQSystemFromStream(F, m, n, xof)
+}
+
+func NewMQSystemFromStream(F Field, m, n int, r io.Reader) (*MQSystem, error) {
+	if m <= 0 || n <= 0 {
+		return nil, ErrDimensions
+	}
+	coeffs := F.Sample(r, Flen(m, n))
+	if coeffs == nil {
+		return nil, ErrEncoding
+	}
+	return &MQSystem{Field: F, M: m, N: n, Coeffs: coeffs}, nil
+}
+
+// NewMQSystemFromUOV converts the UOV block layout (P1i, P2i, P3i as read by
+// MQP) over n variables into an MQSystem.
+func NewMQSystemFromUOV(F Field, m, n int, P1i, P2i, P3i []uint8) (*MQSystem, error) {
+	return NewMQSystemFromOil(F, m, n, m, P1i, P2i, P3i)
+}
+
+// NewMQSystemFromOil assembles m equations in n variables from their UOV
+// blocks for an oil space of dimension o: P1 (v x v, upper triangular), P2
+// (v x o) and P3 (o x o, upper triangular) with v = n - o, one block per
+// equation.
+func NewMQSystemFromOil(F Field, m, n, o int, P1i, P2i, P3i []uint8) (*MQSystem, error) {
+	if m <= 0 || o < 0 || n < o {
+		return nil, ErrDimensions
+	}
+	v := n - o
+	lenP1 := v * (v + 1) / 2
+	lenP2 := v * o
+	lenP3 := o * (o + 1) / 2
+	if len(P1i) != m*lenP1 || len(P2i) != m*lenP2 || len(P3i) != m*lenP3 {
+		return nil, ErrDimensions
+	}
+
+	stride := n * (n + 1) / 2
+	coeffs := make([]uint8, m*stride)
+	for k := 0; k < m; k++ {
+		P1 := NewUpperTriangle(NewDenseMatrix(v, v, P1i[k*lenP1:(k+1)*lenP1]))
+		P2 := NewDenseMatrix(v, o, P2i[k*lenP2:(k+1)*lenP2])
+		P3 := NewUpperTriangle(NewDenseMatrix(o, o, P3i[k*lenP3:(k+1)*lenP3]))
+		pos := k * stride
+		for i := 0; i < n; i++ {
+			for j := i; j < n; j++ {
+				if j < v {
+					coeffs[pos] = P1.At(i, j)
+				} else if i < v {
+					coeffs[pos] = P2.At(i, j-v)
+				} else {
+					coeffs[pos] = P3.At(i-v, j-v)
+				}
+				pos++
+			}
+		}
+	}
+	return NewMQSystem(F, m, n, coeffs)
+}
+
+func (s *MQSystem) Dims() (int, int) {
+	return s.M, s.N
+}
+
+// At returns the coefficient of x_i x_j in equation k, for i <= j.
+func (s *MQSystem) At(k, i, j int) uint8 {
+	if j < i {
+		i, j = j, i
+	}
+	return s.Coeffs[k*s.N*(s.N+1)/2+s.N*i+j-i*(i+1)/2]
+}
+
+func (s *MQSystem) Eval(x []uint8) []uint8 {
+	if len(x) != s.N {
+		return nil
+	}
+	F := s.Field
+	h_prime := make([]uint8, F.Order()*s.M)
+	s.accumulate(h_prime, func(i, j int) uint8 {
+		return F.Mul(x[i], x[j])
+	})
+	return collapse(F, h_prime, s.M)
+}
+
+// Polar evaluates the polar form G(x, y) = F(x + y) - F(x) - F(y).
+func (s *MQSystem) Polar(x, y []uint8) []uint8 {
+	if len(x) != s.N || len(y) != s.N {
+		return nil
+	}
+	F := s.Field
+	h_prime := make([]uint8, F.Order()*s.M)
+	s.accumulate(h_prime, func(i, j int) uint8 {
+		if i == j {
+			return F.Add(F.Mul(x[i], y[i]), F.Mul(x[i], y[i]))
+		}
+		return F.Add(F.Mul(x[i], y[j]), F.Mul(x[j], y[i]))
+	})
+	return collapse(F, h_prime, s.M)
+}
+
+// Bilinear evaluates x^T U y for the upper-triangular matrix U of every
+// equation, so that Bilinear(x, x) = Eval(x).
+func (s *MQSystem) Bilinear(x, y []uint8) []uint8 {
+	if len(x) != s.N || len(y) != s.N {
+		return nil
+	}
+	F := s.Field
+	h_prime := make([]uint8, F.Order()*s.M)
+	s.accumulate(h_prime, func(i, j int) uint8 {
+		return F.Mul(x[i], y[j])
+	})
+	return collapse(F, h_prime, s.M)
+}
+
+// PolarMatrix returns the symmetric matrix Q of equation k, such that
+// x^T Q y is the k-th component of Polar(x, y).
+func (s *MQSystem) PolarMatrix(k int) *Dense {
+	F := s.Field
+	Q := NewDenseMatrix(s.N, s.N, nil)
+	for i := 0; i < s.N; i++ {
+		Q.Set(i, i, F.Add(s.At(k, i, i), s.At(k, i, i)))
+		for j := i + 1; j < s.N; j++ {
+			Q.Set(i, j, s.At(k, i, j))
+			Q.Set(j, i, s.At(k, i, j))
+		}
+	}
+	return Q
+}
+
+// Compose returns S∘F∘T, where the N x n' matrix T is substituted for the
+// variables and the m' x M matrix S mixes the equations. A nil S or T stands
+// for the identity.
+func (s *MQSystem) Compose(S, Tm Matrix) (*MQSystem, error) {
+	F := s.Field
+	m, n := s.M, s.N
+	if Tm != nil {
+		r, c := Tm.Dims()
+		if r != s.N {
+			return nil, ErrDimensions
+		}
+		n = c
+	}
+	if S != nil {
+		r, c := S.Dims()
+		if c != s.M {
+			return nil, ErrDimensions
+		}
+		m = r
+	}
+
+	stride := n * (n + 1) / 2
+	quads := make([][]uint8, s.M)
+	for k := 0; k < s.M; k++ {
+		var Q Matrix = NewUpperTriangle(NewDenseMatrix(s.N, s.N, s.equation(k)))
+		if Tm != nil {
+			Q = MulMatOver(F, MulMatOver(F, T(Tm), Q), Tm)
+		}
+		quads[k] = upper(F, Q)
+	}
+	if S == nil {
+		coeffs := make([]uint8, 0, m*stride)
+		for k := 0; k < m; k++ {
+			coeffs = append(coeffs, quads[k]...)
+		}
+		return NewMQSystem(F, m, n, coeffs)
+	}
+
+	coeffs := make([]uint8, m*stride)
+	for l := 0; l < m; l++ {
+		for k := 0; k < s.M; k++ {
+			slk := S.At(l, k)
+			if slk == 0 {
+				continue
+			}
+			for p, c := range quads[k] {
+				coeffs[l*stride+p] = F.Add(coeffs[l*stride+p], F.Mul(slk, c))
+			}
+		}
+	}
+	return NewMQSystem(F, m, n, coeffs)
+}
+
+// Concat returns the system over the variables (x, y) mapping them to
+// s(x) + o(y), i.e. the P‖R layout used by MQAT.
+func (s *MQSystem) Concat(o *MQSystem) (*MQSystem, error) {
+	if s.Field != o.Field {
+		return nil, ErrFieldMismatch
+	}
+	if s.M != o.M {
+		return nil, ErrDimensions
+	}
+	n := s.N + o.N
+	coeffs := make([]uint8, 0, Flen(s.M, n))
+	for k := 0; k < s.M; k++ {
+		for i := 0; i < n; i++ {
+			for j := i; j < n; j++ {
+				var c uint8
+				if j < s.N {
+					c = s.At(k, i, j)
+				} else if i >= s.N {
+					c = o.At(k, i-s.N, j-s.N)
+				}
+				coeffs = append(coeffs, c)
+			}
+		}
+	}
+	return &MQSystem{Field: s.Field, M: s.M, N: n, Coeffs: coeffs}, nil
+}
+
+func (s *MQSystem) Equal(o *MQSystem) bool {
+	if s == nil || o == nil {
+		return s == o
+	}
+	return s.Field == o.Field && s.M == o.M && s.N == o.N &&
+		bytes.Equal(s.Coeffs, o.Coeffs)
+}
+
+// MarshalBinary encodes the system as the field order (2 bytes), M and N
+// (4 bytes each, big endian) followed by the packed coefficients.
+func (s *MQSystem) MarshalBinary() ([]byte, error) {
+	out := make([]byte, 10, 10+s.Field.PackedLen(len(s.Coeffs)))
+	binary.BigEndian.PutUint16(out[0:2], uint16(s.Field.Order()))
+	binary.BigEndian.PutUint32(out[2:6], uint32(s.M))
+	binary.BigEndian.PutUint32(out[6:10], uint32(s.N))
+	return append(out, s.Field.Pack(s.Coeffs)...), nil
+}
+
+func (s *MQSystem) UnmarshalBinary(data []byte) error {
+	if len(data) < 10 {
+		return ErrEncoding
+	}
+	F, err := FieldByOrder(int(binary.BigEndian.Uint16(data[0:2])))
+	if err != nil {
+		return err
+	}
+	m := int(binary.BigEndian.Uint32(data[2:6]))
+	n := int(binary.BigEndian.Uint32(data[6:10]))
+	if m <= 0 || n <= 0 || len(data)-10 != F.PackedLen(Flen(m, n)) {
+		return ErrEncoding
+	}
+	coeffs := F.Unpack(data[10:], Flen(m, n))
+	if coeffs == nil {
+		return ErrEncoding
+	}
+	sys, err := NewMQSystem(F, m, n, coeffs)
+	if err != nil {
+		return err
+	}
+	*s = *sys
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+func (s *MQSystem) equation(k int) []uint8 {
+	stride := s.N * (s.N + 1) / 2
+	return s.Coeffs[k*stride : (k+1)*stride]
+}
+
+// accumulate adds every coefficient of monomial (i, j) to the bucket indexed
+// by the field element mono(i, j).
+func (s *MQSystem) accumulate(h_prime []uint8, mono func(i, j int) uint8) {
+	F := s.Field
+	m, n := s.M, s.N
+	stride := n * (n + 1) / 2
+	xor := charTwo(F)
+	pos := 0
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			t := int(mono(i, j))
+			bucket := h_prime[t*m : (t+1)*m]
+			for k := 0; k < m; k++ {
+				c := s.Coeffs[k*stride+pos]
+				if xor {
+					bucket[k] ^= c
+				} else {
+					bucket[k] = F.Add(bucket[k], c)
+				}
+			}
+			pos++
+		}
+	}
+}
+
+// upper folds a square matrix into the upper-triangular representation of
+// the same quadratic form.
+func upper(F Field, Q Matrix) []uint8 {
+	n, _ := Q.Dims()
+	res := make([]uint8, 0, n*(n+1)/2)
+	for i := 0; i < n; i++ {
+		res = append(res, Q.At(i, i))
+		for j := i + 1; j < n; j++ {
+			res = append(res, F.Add(Q.At(i, j), Q.At(j, i)))
+		}
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+package test
+
+import (
+	"bytes"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+)
+
+func TestReconciliationAttack(t *testing.T) {
+	for _, tc := range []struct{ m, n int }{
+		{3, 6}, // balanced
+		{3, 8}, // unbalanced, still small enough for exhaustive search
+	} {
+		uov := crypto.NewUOVOver(math.GF16, tc.m, tc.n, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+		sk, pk := uov.KeyGen()
+		O, err := uov.ReconciliationAttack(pk, []byte{1})
+		if err != nil {
+			t.Errorf("m=%d, n=%d: %v", tc.m, tc.n, err)
+			continue
+		}
+		if !bytes.Equal(O, sk.O) {
+			t.Errorf("m=%d, n=%d: recovered oil space differs from the secret key", tc.m, tc.n)
+		}
+	}
+}
+
+func TestKipnisShamirAttack(t *testing.T) {
+	uov := crypto.NewUOVOver(math.GF256, 3, 6, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	sk, pk := uov.KeyGen()
+	O, err := uov.KipnisShamirAttack(pk, 50, []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(O, sk.O) {
+		t.Error("recovered oil space differs from the secret key")
+	}
+
+	// with more vinegar than oil variables the attack is expected to fail
+	uov = crypto.NewUOVOver(math.GF256, 2, 6, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	_, pk = uov.KeyGen()
+	if _, err := uov.KipnisShamirAttack(pk, 10, []byte{1}); err != crypto.ErrAttackFailed {
+		t.Errorf("attack on unbalanced parameters returned %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"bytes"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"os"
+	"strings"
+	"testing"
+)
+
+const toyChallenge = `Galois Field : GF(31)
+Number of variables (n) : 2
+Number of polynomials (m) : 2
+Seed : 7
+Order : graded reverse lex order
+
+*********************
+1 2 3 4 5 6 ;
+0 0 1 0 30 0 ;
+`
+
+func TestReadChallenge(t *testing.T) {
+	c, err := math.ReadChallenge(strings.NewReader(toyChallenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Seed != 7 || !c.Homogenized || c.Variables() != 2 || c.System.Field != math.GF31 {
+		t.Fatalf("unexpected header %+v", c)
+	}
+	F := math.GF31
+	for x1 := 0; x1 < 31; x1 += 5 {
+		for x2 := 0; x2 < 31; x2 += 3 {
+			a, b := uint8(x1), uint8(x2)
+			// x1^2 + 2 x1x2 + 3 x2^2 + 4 x1 + 5 x2 + 6 and x2^2 - x2
+			f0 := F.Add(F.Add(F.Mul(a, a), F.Mul(2, F.Mul(a, b))), F.Mul(3, F.Mul(b, b)))
+			f0 = F.Add(F.Add(f0, F.Mul(4, a)), F.Add(F.Mul(5, b), 6))
+			f1 := F.Sub(F.Mul(b, b), b)
+			got := c.System.Eval([]uint8{a, b, 1})
+			if got[0] != f0 || got[1] != f1 {
+				t.Errorf("f(%d, %d) = %v, expected [%d %d]", a, b, got, f0, f1)
+				return
+			}
+		}
+	}
+}
+
+func TestChallengeRoundTrip(t *testing.T) {
+	for name, F := range fields {
+		sys, _ := math.NewMQSystemFromSeed(F, 4, 6, []byte{1})
+		for _, target := range [][]uint8{nil, sampleOver(F, 4, []byte{2})} {
+			c, err := math.NewChallenge(sys, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Seed = 42
+
+			var buf bytes.Buffer
+			if err := math.WriteChallenge(&buf, c); err != nil {
+				t.Fatal(err)
+			}
+			dec, err := math.ReadChallenge(&buf)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+			if dec.Seed != 42 || dec.Homogenized != c.Homogenized || !dec.System.Equal(c.System) {
+				t.Errorf("%s: text round trip changed the challenge", name)
+			}
+
+			data, _ := c.MarshalBinary()
+			var bin math.Challenge
+			if err := bin.UnmarshalBinary(data); err != nil || !bin.System.Equal(c.System) {
+				t.Errorf("%s: binary round trip changed the challenge", name)
+			}
+		}
+	}
+}
+
+func TestMQATChallenge(t *testing.T) {
+	m, n := 8, 20
+	mqat := crypto.NewMQAT(
+		n, m,
+		constants.SALT_LEN,
+		constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN,
+		16, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN,
+		crypto.Insecure(),
+	)
+	sk, pk := mqat.KeyGen()
+	tok, z_star, query := mqat.User0(pk)
+	resp := mqat.Sign0(sk, query)
+
+	c, err := mqat.Challenge(pk, tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := append(append(bytes.Clone(resp), z_star...), 1)
+	for _, v := range c.System.Eval(x) {
+		if v != 0 {
+			t.Error("token witness is not a solution of the exported challenge")
+			return
+		}
+	}
+}
+
+func BenchmarkChallengeEval(b *testing.B) {
+	f, err := os.Open("testdata/challenge_gf31.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	c, err := math.ReadChallenge(f)
+	if err != nil {
+		b.Fatal(err)
+	}
+	x := sampleOver(c.System.Field, c.System.N, []byte{0})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.System.Eval(x)
+	}
+}
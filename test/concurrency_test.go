@@ -0,0 +1,112 @@
+package test
+
+import (
+	"bytes"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"sync"
+	"testing"
+)
+
+// Run with go test -race: the goroutines share the schemes and the keys.
+func TestConcurrent(t *testing.T) {
+	const workers = 16
+	mqat := crypto.NewMQATOver(math.GF256, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, crypto.Insecure())
+	mqat_sk, mqat_pk := mqat.KeyGen()
+	uov := crypto.NewUOV(16, 48, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	uov_sk, uov_pk := uov.KeyGen()
+	mqdss := crypto.NewMQDSS(16, 32, 16, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, crypto.Insecure())
+	mqdss_sk, mqdss_pk := mqdss.KeyGen()
+	O, Si := bytes.Clone(uov_sk.O), bytes.Clone(uov_sk.Si)
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 3*workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tok, z_star, query := mqat.User0(mqat_pk)
+			resp := mqat.Sign0(mqat_sk, query)
+			token := mqat.User1(mqat_pk, tok, z_star, resp)
+			if token == nil || !mqat.Verify(mqat_pk, token) {
+				errs <- "token does not verify"
+			}
+
+			msg := crypto.Nrand128(uov.M, []byte{byte(i)})
+			sig, err := uov.SignChecked(msg, uov_sk, uov_pk)
+			if err != nil || !uov.Verify(msg, sig, uov_pk) {
+				errs <- "UOV signature does not verify"
+			}
+
+			m := []byte{byte(i)}
+			if !mqdss.Verify(m, mqdss.Sign(m, mqdss_sk), mqdss_pk) {
+				errs <- "MQDSS signature does not verify"
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if !bytes.Equal(O, uov_sk.O) || !bytes.Equal(Si, uov_sk.Si) {
+		t.Error("UOV secret key modified")
+	}
+}
+
+// The inputs are given spare capacity holding a sentinel, which an append
+// onto them would overwrite.
+func TestNoAliasing(t *testing.T) {
+	spare := func(s []uint8, extra int) []uint8 {
+		res := make([]uint8, len(s), len(s)+extra)
+		copy(res, s)
+		for i := len(s); i < cap(res); i++ {
+			res[:cap(res)][i] = 0xff
+		}
+		return res
+	}
+	intact := func(s []uint8) bool {
+		for _, a := range s[len(s):cap(s)] {
+			if a != 0xff {
+				return false
+			}
+		}
+		return true
+	}
+
+	uov := crypto.NewUOV(16, 48, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	sk, pk := uov.KeyGen()
+	// no buffer of the secret key is shared with the public key, which
+	// must survive Destroy
+	pk_fields := [][]uint8{pk.Seed, pk.P1i, pk.P2i, pk.P3i}
+	saved := make([][]uint8, len(pk_fields))
+	for i, f := range pk_fields {
+		saved[i] = bytes.Clone(f)
+	}
+	for _, f := range [][]uint8{sk.Seed, sk.PkSeed, sk.O, sk.Si, sk.P1i} {
+		f[0] ^= 1
+		for i := range pk_fields {
+			if !bytes.Equal(pk_fields[i], saved[i]) {
+				t.Error("UOV secret and public keys share a buffer")
+			}
+		}
+		f[0] ^= 1
+	}
+	sk.O = spare(sk.O, uov.M*uov.M)
+	msg := spare(crypto.Nrand128(uov.M, []byte{0}), uov.N)
+	if sig := uov.Sign(msg, sk); !uov.Verify(msg, sig, pk) || !intact(sk.O) || !intact(msg) {
+		t.Error("UOV.Sign wrote to its inputs")
+	}
+
+	mqat := crypto.NewMQATOver(math.GF256, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, crypto.Insecure())
+	mqat_sk, mqat_pk := mqat.KeyGen()
+	tok, z_star, query := mqat.User0(mqat_pk)
+	resp := spare(mqat.Sign0(mqat_sk, spare(query, 8)), len(z_star))
+	z := bytes.Clone(z_star)
+	if token := mqat.User1(mqat_pk, tok, z_star, resp); token == nil || !intact(resp) || !bytes.Equal(z, z_star) {
+		t.Error("MQAT.User1 wrote to its inputs")
+	}
+}
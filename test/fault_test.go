@@ -0,0 +1,135 @@
+package test
+
+import (
+	"errors"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+)
+
+// faultyField flips the low bit of the result of its at-th multiplication,
+// counting from when it is armed, simulating a single fault in the signer.
+type faultyField struct {
+	math.Field
+	at, calls int
+}
+
+func (f *faultyField) Mul(a, b uint8) uint8 {
+	c := f.Field.Mul(a, b)
+	f.calls++
+	if f.calls == f.at {
+		c ^= 1
+	}
+	return c
+}
+
+func (f *faultyField) arm(at int) {
+	f.at, f.calls = at, 0
+}
+
+func TestUOVSignChecked(t *testing.T) {
+	F := &faultyField{Field: math.GF256}
+	uov := crypto.NewUOVOver(F, 8, 24, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	honest := crypto.NewUOVOver(math.GF256, 8, 24, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	sk, pk := uov.KeyGen()
+	msg := crypto.Nrand128(uov.M, []byte{2})
+
+	if sig, err := uov.SignChecked(msg, sk, pk); err != nil || !honest.Verify(msg, sig, pk) {
+		t.Fatalf("fault-free signature: %v", err)
+	}
+	// multiplications of Sign, then of SignChecked
+	F.arm(0)
+	uov.Sign(msg, sk)
+	signing := F.calls
+	F.arm(0)
+	uov.SignChecked(msg, sk, pk)
+	total := F.calls
+
+	detected, leaked := 0, 0
+	for at := 1; at <= total; at += total/200 + 1 {
+		F.arm(at)
+		sig, err := uov.SignChecked(msg, sk, pk)
+		switch {
+		case errors.Is(err, crypto.ErrFault):
+			detected++
+		case err != nil:
+			t.Errorf("fault at %d: %v", at, err)
+		case !honest.Verify(msg, sig, pk):
+			t.Fatalf("fault at %d: faulty signature released", at)
+		}
+		if at <= signing {
+			F.arm(at)
+			if sig := uov.Sign(msg, sk); sig != nil && !honest.Verify(msg, sig, pk) {
+				leaked++
+			}
+		}
+	}
+	if detected == 0 || leaked == 0 {
+		t.Errorf("%d faults detected, %d faulty signatures from Sign", detected, leaked)
+	}
+	t.Logf("%d of %d multiplications in signing, %d faults detected, %d faulty signatures from Sign", signing, total, detected, leaked)
+}
+
+func TestUOVSignCheckedKeyFault(t *testing.T) {
+	uov := crypto.NewUOV(8, 24, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	sk, pk := uov.KeyGen()
+	msg := crypto.Nrand128(uov.M, []byte{3})
+	// a bit flip in the stored key is invisible to the redundant
+	// computation, which uses the same key, but not to the verification;
+	// the flip has no effect when the variable it multiplies is zero, and
+	// the signature is then valid
+	for _, part := range [][]uint8{sk.O, sk.Si, sk.P1i} {
+		part[len(part)/2] ^= 0x10
+		detected := 0
+		for i := 0; i < 4; i++ {
+			m := crypto.Nrand128(uov.M, []byte{4, byte(i)})
+			sig, err := uov.SignChecked(m, sk, pk)
+			switch {
+			case errors.Is(err, crypto.ErrFault):
+				detected++
+			case err != nil || !uov.Verify(m, sig, pk):
+				t.Errorf("flipped key: %v, faulty signature released", err)
+			}
+		}
+		if detected == 0 {
+			t.Error("flipped key: no fault detected")
+		}
+		part[len(part)/2] ^= 0x10
+	}
+	if _, err := uov.SignChecked(msg[1:], sk, pk); !errors.Is(err, crypto.ErrSignFailed) {
+		t.Errorf("short message: %v", err)
+	}
+}
+
+func TestMQATSign0Checked(t *testing.T) {
+	F := &faultyField{Field: math.GF256}
+	for _, opts := range [][]crypto.Option{{crypto.Insecure()}, {crypto.WithMAYO(4, 5), crypto.Insecure()}} {
+		mqat := crypto.NewMQATOver(F, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+			constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, opts...)
+		F.arm(0)
+		sk, pk := mqat.KeyGen()
+		tok, z_star, query := mqat.User0(pk)
+		F.arm(0)
+		mqat.Sign0(sk, query)
+		signing := F.calls
+
+		detected := 0
+		for at := 1; at <= signing; at += signing/50 + 1 {
+			F.arm(at)
+			resp, err := mqat.Sign0Checked(sk, pk, query)
+			F.arm(0)
+			switch {
+			case errors.Is(err, crypto.ErrFault):
+				detected++
+			case err != nil:
+				t.Errorf("fault at %d: %v", at, err)
+			case mqat.User1(pk, tok, z_star, resp) == nil:
+				t.Fatalf("fault at %d: faulty response released", at)
+			}
+		}
+		if detected == 0 {
+			t.Errorf("%d multiplications, no fault detected", signing)
+		}
+	}
+}
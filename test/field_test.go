@@ -0,0 +1,225 @@
+package test
+
+import (
+	"bytes"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var fields = map[string]math.Field{
+	"GF16":  math.GF16,
+	"GF31":  math.GF31,
+	"GF256": math.GF256,
+}
+
+func TestFieldAxioms(t *testing.T) {
+	for name, F := range fields {
+		q := F.Order()
+		for a := 0; a < q; a++ {
+			x := uint8(a)
+			if F.Add(x, F.Neg(x)) != 0 {
+				t.Errorf("%s: %d + (-%d) != 0", name, x, x)
+				return
+			}
+			if x != 0 && F.Mul(x, F.Inv(x)) != 1 {
+				t.Errorf("%s: %d * %d != 1", name, x, F.Inv(x))
+				return
+			}
+			for b := 0; b < q; b++ {
+				y := uint8(b)
+				if F.Mul(x, y) != F.Mul(y, x) {
+					t.Errorf("%s: multiplication is not commutative", name)
+					return
+				}
+				if F.Sub(F.Add(x, y), y) != x {
+					t.Errorf("%s: (%d + %d) - %d != %d", name, x, y, y, x)
+					return
+				}
+				z := uint8((a + b) % q)
+				if F.Mul(x, F.Add(y, z)) != F.Add(F.Mul(x, y), F.Mul(x, z)) {
+					t.Errorf("%s: multiplication is not distributive", name)
+					return
+				}
+			}
+		}
+	}
+}
+
+func TestFieldPacking(t *testing.T) {
+	for name, F := range fields {
+		for _, n := range []int{1, 2, 7, 64} {
+			x := sampleOver(F, n, []byte{byte(n)})
+			packed := F.Pack(x)
+			if len(packed) != F.PackedLen(n) {
+				t.Errorf("%s: packed length %d, expected %d", name, len(packed), F.PackedLen(n))
+				return
+			}
+			for _, v := range x {
+				if int(v) >= F.Order() {
+					t.Errorf("%s: sampled element %d out of range", name, v)
+					return
+				}
+			}
+			if !bytes.Equal(x, F.Unpack(packed, n)) {
+				t.Errorf("%s: unpack(pack(x)) != x", name)
+				return
+			}
+		}
+	}
+}
+
+func TestSolveOver(t *testing.T) {
+	for name, F := range fields {
+		l := 6
+		x := sampleOver(F, l, []byte{1})
+		for ctr := byte(0); ; ctr++ {
+			A := math.NewDenseMatrix(l, l, sampleOver(F, l*l, []byte{2, ctr}))
+			b := math.MulMatOver(F, A, math.NewVector(x))
+			xPrime := math.SolveOver(F, A, math.NewVector(b.Data))
+			if xPrime.Data == nil {
+				// singular matrix, try another one
+				continue
+			}
+			if !bytes.Equal(x, xPrime.Data) {
+				t.Errorf("%s: solution does not match", name)
+			}
+			break
+		}
+	}
+}
+
+func TestUOVOverFields(t *testing.T) {
+	m, n := 8, 20
+	for name, F := range fields {
+		uov := crypto.NewUOVOver(F, m, n, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+		sk, pk := uov.KeyGen()
+		target := sampleOver(F, m, []byte{3})
+		sig := uov.Sign(target, sk)
+		if sig == nil {
+			t.Errorf("%s: could not sign", name)
+			continue
+		}
+		if !uov.Verify(target, sig, pk) {
+			t.Errorf("%s: signature does not verify", name)
+		}
+	}
+}
+
+func TestMQATOverFields(t *testing.T) {
+	m, n := 8, 20
+	for name, F := range fields {
+		mqat := crypto.NewMQATOver(
+			F,
+			n, m,
+			constants.SALT_LEN,
+			constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+			constants.RANDOM_SYS_SEED_LEN,
+			16, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN,
+			crypto.Insecure(),
+		)
+		sk, pk := mqat.KeyGen()
+		tok, z_star, query := mqat.User0(pk)
+		resp := mqat.Sign0(sk, query)
+		if resp == nil {
+			t.Errorf("%s: issuer could not sign", name)
+			continue
+		}
+		token := mqat.User1(pk, tok, z_star, resp)
+		if token == nil {
+			t.Errorf("%s: could not finalize token", name)
+			continue
+		}
+		if !mqat.Verify(pk, token) {
+			t.Errorf("%s: token does not verify", name)
+		}
+		token.Token[0] ^= 1
+		if mqat.Verify(pk, token) {
+			t.Errorf("%s: modified token verifies", name)
+		}
+	}
+}
+
+func TestExtensionAxioms(t *testing.T) {
+	for name, F := range fields {
+		for _, k := range []int{2, 3} {
+			E, err := math.NewExtension(F, k)
+			if err != nil {
+				t.Fatalf("%s^%d: %v", name, k, err)
+			}
+			xs := E.Sample(bytes.NewReader(crypto.Nrand256(1000, []byte{byte(k)})), 30)
+			one := E.Embed(1)
+			for i := 0; i+2 < len(xs)/k; i++ {
+				a, b, c := xs[i*k:(i+1)*k], xs[(i+1)*k:(i+2)*k], xs[(i+2)*k:(i+3)*k]
+				if !bytes.Equal(E.Mul(a, E.Add(b, c)), E.Add(E.Mul(a, b), E.Mul(a, c))) {
+					t.Errorf("%s^%d: multiplication does not distribute", name, k)
+				}
+				if !bytes.Equal(E.Mul(a, b), E.Mul(b, a)) {
+					t.Errorf("%s^%d: multiplication does not commute", name, k)
+				}
+				if !E.IsZero(a) && !bytes.Equal(E.Mul(a, E.Inv(a)), one) {
+					t.Errorf("%s^%d: wrong inverse", name, k)
+				}
+			}
+		}
+	}
+	if _, err := math.NewExtension(math.GF256, 8); err != math.ErrDimensions {
+		t.Errorf("GF256^8 returned %v", err)
+	}
+}
+
+func TestMQATProofVariants(t *testing.T) {
+	m, n := 8, 20
+	variants := map[string][]crypto.Option{
+		"extension": {crypto.WithExtension(2)},
+		"compact":   {crypto.Compact()},
+		"both":      {crypto.WithExtension(2), crypto.Compact()},
+		"mqom":      {crypto.WithExtension(2), crypto.WithMQOM(3)},
+	}
+	for name, F := range fields {
+		for variant, opts := range variants {
+			mqat := crypto.NewMQATOver(
+				F,
+				n, m,
+				constants.SALT_LEN,
+				constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+				constants.RANDOM_SYS_SEED_LEN,
+				16, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN,
+				append(opts, crypto.Insecure())...,
+			)
+			sk, pk := mqat.KeyGen()
+			tok, z_star, query := mqat.User0(pk)
+			resp := mqat.Sign0(sk, query)
+			token := mqat.User1(pk, tok, z_star, resp)
+			if token == nil {
+				t.Errorf("%s, %s: could not finalize token", name, variant)
+				continue
+			}
+			if size := len(token.Token) + len(token.MQDSSSignature); size > mqat.TokenSize() {
+				t.Errorf("%s, %s: token of %d bytes, expected at most %d", name, variant, size, mqat.TokenSize())
+			}
+			if !mqat.Verify(pk, token) {
+				t.Errorf("%s, %s: token does not verify", name, variant)
+			}
+			token.MQDSSSignature[len(token.MQDSSSignature)/3] ^= 1
+			if mqat.Verify(pk, token) {
+				t.Errorf("%s, %s: modified token verifies", name, variant)
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// sampleOver samples n elements of F from SHAKE128 of the seed, as
+// math.NewMQSystemFromSeed does.
+func sampleOver(F math.Field, n int, seed []byte) []uint8 {
+	xof := sha3.NewShake128()
+	xof.Write(seed)
+	return F.Sample(xof, n)
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	constants "mqat/const"
+	"mqat/crypto"
+	"testing"
+)
+
+// run drives one run of the protocol, tampering with the response if asked.
+func run(t *testing.T, p *crypto.IDProver, v *crypto.IDVerifier, tamper bool) (bool, int) {
+	commitment, err := p.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alphas, err := v.ChallengeAlphas(commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := p.Respond(alphas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tamper {
+		response[0] ^= 1
+	}
+	bits, err := v.ChallengeBits(response)
+	if tamper && err == crypto.ErrMalformedMessage {
+		// the flipped bit took a GF31 entry out of the field
+		return false, 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	opening, err := p.Open(bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v.Verify(opening), len(commitment) + len(response) + len(opening)
+}
+
+func TestInteractive(t *testing.T) {
+	for name, F := range fields {
+		mqdss := crypto.NewMQDSSOver(F, 8, 28, 32, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN,
+			crypto.WithExtension(2), crypto.Interactive(), crypto.Insecure())
+		sk, pk := mqdss.KeyGen()
+		if ok, _ := run(t, mqdss.NewProver(sk), mqdss.NewVerifier(pk), false); !ok {
+			t.Errorf("%s: honest prover rejected", name)
+		}
+		if ok, _ := run(t, mqdss.NewProver(sk), mqdss.NewVerifier(pk), true); ok {
+			t.Errorf("%s: tampered response accepted", name)
+		}
+		other, _ := mqdss.KeyGen()
+		if ok, _ := run(t, mqdss.NewProver(other), mqdss.NewVerifier(pk), false); ok {
+			t.Errorf("%s: prover with a wrong witness accepted", name)
+		}
+		if mqdss.Sign([]byte("message"), sk) != nil {
+			t.Errorf("%s: interactive MQDSS signs", name)
+		}
+	}
+}
+
+func TestInteractiveOutOfOrder(t *testing.T) {
+	mqdss := crypto.NewMQDSSOver(fields["GF256"], 8, 28, 32, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN,
+		crypto.Interactive(), crypto.Insecure())
+	sk, pk := mqdss.KeyGen()
+	p, v := mqdss.NewProver(sk), mqdss.NewVerifier(pk)
+	if _, err := p.Respond(make([]byte, 32)); err != crypto.ErrProtocolState {
+		t.Errorf("respond before commit: %v", err)
+	}
+	if _, err := v.ChallengeBits(nil); err != crypto.ErrProtocolState {
+		t.Errorf("bits before alphas: %v", err)
+	}
+	commitment, _ := p.Commit()
+	if _, err := p.Commit(); err != crypto.ErrProtocolState {
+		t.Errorf("commit twice: %v", err)
+	}
+	if _, err := v.ChallengeAlphas(commitment[1:]); err != crypto.ErrMalformedMessage {
+		t.Errorf("short commitment: %v", err)
+	}
+	alphas, _ := v.ChallengeAlphas(commitment)
+	if _, err := p.Respond(make([]byte, len(alphas))); err != crypto.ErrMalformedMessage {
+		t.Errorf("zero alphas: %v", err)
+	}
+	if v.Verify(nil) {
+		t.Error("verified before the opening")
+	}
+}
+
+func TestMQATInteractiveRedemption(t *testing.T) {
+	mqat := crypto.MQAT_GF256.New()
+	sk, pk := mqat.KeyGen()
+	tok, z_star, query := mqat.User0(pk)
+	resp := mqat.Sign0(sk, query)
+
+	ok, size := run(t, mqat.NewRedeemer(pk, tok, z_star, resp), mqat.NewRedemptionVerifier(pk, tok), false)
+	if !ok {
+		t.Fatal("redemption rejected")
+	}
+	if size >= mqat.TokenSize() {
+		t.Errorf("redemption of %d bytes, token of %d bytes", size, mqat.TokenSize())
+	}
+	t.Logf("interactive redemption: %d bytes, token: %d bytes", size, mqat.TokenSize())
+
+	resp[0] ^= 1
+	if mqat.NewRedeemer(pk, tok, z_star, resp) != nil {
+		t.Error("redeemer accepted an invalid response")
+	}
+	if r := crypto.InteractiveRounds(255, 128); crypto.InteractiveBits(255, r) < 128 || r >= crypto.MQDSSRounds(256, 128) {
+		t.Errorf("%d interactive rounds", r)
+	}
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+	"time"
+)
+
+func TestKeyFile(t *testing.T) {
+	mqat := crypto.NewMQATOver(math.GF256, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, crypto.Insecure())
+	sk, pk := mqat.KeyGen()
+	pass := []byte("correct horse battery staple")
+	file, err := mqat.EncryptSecretKey(sk, pk, pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := crypto.ReadKeyFileHeader(file)
+	if err != nil || header.ParameterSet != "" || !bytes.Equal(header.KeyID, mqat.KeyID(pk)) ||
+		time.Since(header.Created) > time.Minute {
+		t.Fatalf("header %+v: %v", header, err)
+	}
+
+	loaded_sk, loaded_pk, err := mqat.DecryptSecretKey(file, pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mqat.ValidateKeys(loaded_sk, pk); err != nil {
+		t.Error(err)
+	}
+	tok, z_star, query := mqat.User0(loaded_pk)
+	token := mqat.User1(pk, tok, z_star, mqat.Sign0(loaded_sk, query))
+	if token == nil || !mqat.Verify(pk, token) {
+		t.Error("loaded key does not issue")
+	}
+
+	if _, _, err := mqat.DecryptSecretKey(file, []byte("wrong")); !errors.Is(err, crypto.ErrPassphrase) {
+		t.Errorf("wrong passphrase: %v", err)
+	}
+	// the header is authenticated: here the creation time
+	for _, i := range []int{len(file) - 1, len("MQATKEY") + 2 + 16 + 7} {
+		file[i] ^= 1
+		if _, _, err := mqat.DecryptSecretKey(file, pass); !errors.Is(err, crypto.ErrPassphrase) {
+			t.Errorf("modified byte %d: %v", i, err)
+		}
+		file[i] ^= 1
+	}
+	for _, f := range [][]byte{nil, file[:40], append([]byte("MQATKEZ"), file[7:]...)} {
+		if _, _, err := mqat.DecryptSecretKey(f, pass); !errors.Is(err, crypto.ErrKeyFile) {
+			t.Errorf("malformed file of %d bytes: %v", len(f), err)
+		}
+	}
+
+	new_pass := []byte("new passphrase")
+	reencrypted, err := crypto.ReencryptKeyFile(file, pass, new_pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mqat.DecryptSecretKey(reencrypted, pass); !errors.Is(err, crypto.ErrPassphrase) {
+		t.Errorf("old passphrase opens the re-encrypted file: %v", err)
+	}
+	if _, _, err := mqat.DecryptSecretKey(reencrypted, new_pass); err != nil {
+		t.Error(err)
+	}
+	if h, err := crypto.ReadKeyFileHeader(reencrypted); err != nil || !bytes.Equal(h.KeyID, header.KeyID) || !h.Created.Equal(header.Created) {
+		t.Errorf("header not kept: %+v", h)
+	}
+	if _, err := crypto.ReencryptKeyFile(file, new_pass, pass); !errors.Is(err, crypto.ErrPassphrase) {
+		t.Errorf("re-encrypted with the wrong passphrase: %v", err)
+	}
+
+	mayo := crypto.NewMQATOver(math.GF256, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, crypto.WithMAYO(4, 5), crypto.Insecure())
+	if _, _, err := mayo.DecryptSecretKey(file, pass); !errors.Is(err, crypto.ErrKeyFileParams) {
+		t.Errorf("UOV key loaded by a MAYO issuer: %v", err)
+	}
+}
+
+func TestKeyFileParameterSet(t *testing.T) {
+	p, ok := crypto.ParameterSetByName(crypto.MQAT_GF256_MAYO.Name)
+	if !ok {
+		t.Fatal("parameter set not found")
+	}
+	mqat := p.New()
+	sk, pk := mqat.KeyGen()
+	file, err := mqat.EncryptSecretKey(sk, pk, []byte("pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := crypto.ReadKeyFileHeader(file)
+	if err != nil || header.ParameterSet != p.Name {
+		t.Fatalf("header %+v: %v", header, err)
+	}
+	q, _ := crypto.ParameterSetByName(header.ParameterSet)
+	if _, loaded_pk, err := q.New().DecryptSecretKey(file, []byte("pass")); err != nil || !bytes.Equal(q.New().KeyID(loaded_pk), header.KeyID) {
+		t.Errorf("could not load with the set of the header: %v", err)
+	}
+	if _, _, err := crypto.MQAT_GF256_COMPACT.New().DecryptSecretKey(file, []byte("pass")); !errors.Is(err, crypto.ErrKeyFileParams) {
+		t.Errorf("loaded by another parameter set: %v", err)
+	}
+	if _, err := mqat.EncryptSecretKey(sk, nil, []byte("pass")); err == nil {
+		t.Error("encrypted a key without its public key")
+	}
+}
+
+// A cost above the bounds is rejected from the header alone: deriving the
+// key with the larger ones would not finish or not fit in memory.
+func TestKeyFileCost(t *testing.T) {
+	mqat := crypto.NewMQATOver(math.GF256, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, crypto.Insecure())
+	sk, pk := mqat.KeyGen()
+	pass := []byte("pass")
+	file, err := mqat.EncryptSecretKey(sk, pk, pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// time and memory in KiB follow the key ID and the creation time
+	off := len("MQATKEY") + 2 + 16 + 8
+	for _, cost := range [][2]uint32{{9, 64 * 1024}, {1 << 31, 64 * 1024}, {3, 256*1024 + 1}, {3, 1<<32 - 1}} {
+		f := bytes.Clone(file)
+		binary.BigEndian.PutUint32(f[off:], cost[0])
+		binary.BigEndian.PutUint32(f[off+4:], cost[1])
+		if _, err := crypto.ReadKeyFileHeader(f); !errors.Is(err, crypto.ErrKeyFile) {
+			t.Errorf("cost %v: header read: %v", cost, err)
+		}
+		if _, _, err := mqat.DecryptSecretKey(f, pass); !errors.Is(err, crypto.ErrKeyFile) {
+			t.Errorf("cost %v: decrypted: %v", cost, err)
+		}
+		if _, err := crypto.ReencryptKeyFile(f, pass, pass); !errors.Is(err, crypto.ErrKeyFile) {
+			t.Errorf("cost %v: re-encrypted: %v", cost, err)
+		}
+	}
+	// the bounds themselves are accepted
+	f := bytes.Clone(file)
+	binary.BigEndian.PutUint32(f[off:], 8)
+	binary.BigEndian.PutUint32(f[off+4:], 256*1024)
+	if _, err := crypto.ReadKeyFileHeader(f); err != nil {
+		t.Errorf("maximal cost: %v", err)
+	}
+}
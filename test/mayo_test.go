@@ -0,0 +1,76 @@
+package test
+
+import (
+	"bytes"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+)
+
+func TestMAYO(t *testing.T) {
+	for _, F := range []math.Field{math.GF256, math.GF16, math.GF31} {
+		mayo := crypto.NewMAYOOver(F, 16, 20, 4, 5, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+		sk, pk := mayo.KeyGen()
+		if err := mayo.ValidateSecretKey(sk, pk); err != nil {
+			t.Fatalf("%d: %v", F.Order(), err)
+		}
+		target := sampleOver(F, mayo.M, []byte{1})
+		sig := mayo.Sign(target, sk)
+		if sig == nil || !mayo.Verify(target, sig, pk) {
+			t.Fatalf("%d: signature does not verify", F.Order())
+		}
+		// a single copy of the small oil space has no preimage
+		P, _ := mayo.PublicMap(pk)
+		if bytes.Equal(P.Eval(sig[:mayo.N]), target) {
+			t.Errorf("%d: the first copy alone is a preimage", F.Order())
+		}
+		W, err := mayo.WhippedMap(pk)
+		if err != nil || !bytes.Equal(W.Eval(sig), target) {
+			t.Errorf("%d: whipped map disagrees with Verify", F.Order())
+		}
+		sig[0] = F.Add(sig[0], 1)
+		if mayo.Verify(target, sig, pk) {
+			t.Errorf("%d: modified signature verifies", F.Order())
+		}
+	}
+	if crypto.NewMAYO(16, 20, 3, 5, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure()) != nil {
+		t.Error("accepted k*o < m")
+	}
+	// n-o < m: the oil space is found by solving P in n-o variables
+	if crypto.NewMAYO(48, 36, 8, 6, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN) != nil {
+		t.Error("accepted n-o < m")
+	}
+	if bits := crypto.EstimateMAYO(256, 48, 36, 8, 6).Reconciliation; bits > crypto.DirectAttackBits(256, 48, 28) {
+		t.Errorf("n-o < m estimated at %.1f bits", bits)
+	}
+}
+
+func TestMQATMAYO(t *testing.T) {
+	p := crypto.MQAT_GF256_MAYO
+	mqat := p.New()
+	if mqat == nil {
+		t.Fatal("could not instantiate MQAT with MAYO")
+	}
+	sk, pk := mqat.KeyGen()
+	if err := mqat.ValidateKeys(sk, pk); err != nil {
+		t.Fatal(err)
+	}
+	tok, z_star, query := mqat.User0(pk)
+	resp := mqat.Sign0(sk, query)
+	if len(resp) != p.MAYOWhip*p.N {
+		t.Fatalf("response of %d elements", len(resp))
+	}
+	token := mqat.User1(pk, tok, z_star, resp)
+	if token == nil || !mqat.Verify(pk, token) {
+		t.Fatal("token does not verify")
+	}
+	broken := p
+	broken.N, broken.MAYOOil, broken.MAYOWhip = 36, 8, 6
+	if broken.New() != nil {
+		t.Error("accepted a set with n-o < m")
+	}
+	mayo := crypto.NewMAYO(p.M, p.N, p.MAYOOil, p.MAYOWhip, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN)
+	t.Logf("%s: issuer public key of %d bytes, tokens of %d bytes (%s: %d bytes)",
+		p.Name, mayo.PublicKeySize(), mqat.TokenSize(), crypto.MQAT_GF256.Name, crypto.MQAT_GF256.TokenSize())
+}
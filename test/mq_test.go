@@ -1,6 +1,7 @@
 package test
 
 import (
+	"bytes"
 	"crypto/rand"
 	constants "mqat/const"
 	"mqat/crypto"
@@ -102,3 +103,59 @@ func TestG(t *testing.T) {
 		}
 	}
 }
+
+func TestMQChecked(t *testing.T) {
+	n, m := 20, 8
+	x := crypto.Nrand256(n+m, []byte{0})
+	y := crypto.Nrand256(n+m, []byte{2})
+	R := crypto.Nrand128(math.Flen(m, m), []byte{1})
+	P := crypto.Nrand128(math.Flen(m, n), []byte{3})
+	lenP1, lenP2 := m*(n-m)*(n-m+1)/2, m*m*(n-m)
+	P1, P2, P3 := P[:lenP1], P[lenP1:lenP1+lenP2], P[lenP1+lenP2:]
+	F := math.GF256
+
+	fx, err := math.MQChecked(F, P1, P2, P3, R, x, m, n)
+	if err != nil || !bytes.Equal(fx, math.MQ(P1, P2, P3, R, x, m, n)) {
+		t.Errorf("MQChecked: %v", err)
+	}
+	gxy, err := math.GChecked(F, P1, P2, P3, R, x, y, m, n)
+	if err != nil || !bytes.Equal(gxy, math.G(P1, P2, P3, R, x, y, m, n)) {
+		t.Errorf("GChecked: %v", err)
+	}
+	if _, err := math.MQPChecked(F, P1, P2, P3, x[:n], m); err != nil {
+		t.Errorf("MQPChecked: %v", err)
+	}
+	if _, err := math.MQRChecked(F, R, x[n:], m); err != nil {
+		t.Errorf("MQRChecked: %v", err)
+	}
+
+	// x and y over n+m-1 or n+m+1 variables with an R to match, which GOver
+	// would index past them or truncate
+	short, long := math.Flen(m, m-1), crypto.Nrand256(n+m+1, []byte{4})
+	// truncated keys and vectors, a key for other dimensions
+	for name, err := range map[string]error{
+		"short P1":  second(math.MQChecked(F, P1[1:], P2, P3, R, x, m, n)),
+		"short P3":  second(math.MQPChecked(F, P1, P2, P3[:len(P3)-1], x[:n], m)),
+		"short x":   second(math.MQChecked(F, P1, P2, P3, R, x[:n+m-1], m, n)),
+		"no R part": second(math.MQChecked(F, P1, P2, P3, R, x[:n], m, n)),
+		"short R":   second(math.MQRChecked(F, R[1:], x[n:], m)),
+		"wrong m":   second(math.MQPChecked(F, P1, P2, P3, x[:n], m+1)),
+		"wrong n":   second(math.MQChecked(F, P1, P2, P3, R, x, m, n+1)),
+		"short y":   second(math.GChecked(F, P1, P2, P3, R, x, y[1:], m, n)),
+		"short G":   second(math.GChecked(F, P1, P2, P3, R[:short], x[:n+m-1], y[:n+m-1], m, n)),
+		"long G":    second(math.GChecked(F, P1, P2, P3, crypto.Nrand128(math.Flen(m, m+1), []byte{5}), long, long, m, n)),
+		"m > n":     second(math.MQPChecked(F, P1, P2, P3, x[:m-1], m)),
+	} {
+		if err != math.ErrDimensions {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	// GF256 coefficients are not GF31 elements
+	if _, err := math.MQRChecked(math.GF31, R, x[n:], m); err != math.ErrCoefficients {
+		t.Errorf("GF31: %v", err)
+	}
+}
+
+func second(_ []uint8, err error) error {
+	return err
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	gomath "math"
 	constants "mqat/const"
 	"mqat/crypto"
 	"mqat/math"
@@ -92,3 +93,73 @@ func TestMQDSSCorrectness(t *testing.T) {
 		}
 	}
 }
+
+func TestMQDSSCompact(t *testing.T) {
+	for name, F := range fields {
+		for _, k := range []int{1, 2} {
+			mqdss := crypto.NewMQDSSOver(F, 8, 28, 32, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN,
+				crypto.WithExtension(k), crypto.Compact(), crypto.Insecure())
+			sk, pk := mqdss.KeyGen()
+			msg := []byte("message")
+			sig := mqdss.Sign(msg, sk)
+			if len(sig) > mqdss.SignatureSize() {
+				t.Errorf("%s^%d: signature of %d bytes, maximum %d", name, k, len(sig), mqdss.SignatureSize())
+			}
+			if !mqdss.Verify(msg, sig, pk) {
+				t.Errorf("%s^%d: signature does not verify", name, k)
+				continue
+			}
+			if mqdss.Verify([]byte("other message"), sig, pk) {
+				t.Errorf("%s^%d: signature verifies for another message", name, k)
+			}
+			for _, pos := range []int{0, 40, 70, len(sig) / 2, len(sig) - 1} {
+				bad := append([]byte{}, sig...)
+				bad[pos] ^= 1
+				if mqdss.Verify(msg, bad, pk) {
+					t.Errorf("%s^%d: signature modified at byte %d verifies", name, k, pos)
+				}
+			}
+			if mqdss.Verify(msg, sig[:len(sig)-1], pk) || mqdss.Verify(msg, append(sig, 0), pk) {
+				t.Errorf("%s^%d: signature of the wrong length verifies", name, k)
+			}
+		}
+	}
+}
+
+// With a binary second challenge half of the rounds reveal their responses
+// and every round its unopened commitment, so the compact format is bounded
+// below by about half the original size; the severalfold reduction comes
+// with MQOM.
+func TestMQDSSCompactSize(t *testing.T) {
+	mqdss := crypto.NewMQDSS(constants.M, constants.N+constants.M, constants.MQDSS_ROUNDS,
+		constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN)
+	compact := crypto.NewMQDSS(constants.M, constants.N+constants.M, constants.MQDSS_ROUNDS,
+		constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, crypto.Compact())
+	sk, pk := compact.KeyGen()
+	// the size follows the number of opened rounds, so the mean is checked
+	// within four of its standard errors
+	const sigs = 8
+	var sum, sumSq float64
+	for i := 0; i < sigs; i++ {
+		msg := []byte{byte(i)}
+		sig := compact.Sign(msg, sk)
+		if !compact.Verify(msg, sig, pk) {
+			t.Fatal("signature does not verify")
+		}
+		sum += float64(len(sig))
+		sumSq += float64(len(sig)) * float64(len(sig))
+	}
+	mean, expected, plain := sum/sigs, compact.ExpectedSignatureSize(), mqdss.SignatureSize()
+	stderr := gomath.Sqrt((sumSq/sigs - mean*mean) / (sigs - 1))
+	t.Logf("%.0f-byte signatures on average (standard error %.0f), %d expected, at most %d; %d bytes in the original format",
+		mean, stderr, expected, compact.SignatureSize(), plain)
+	if gomath.Abs(mean-float64(expected)) > 4*stderr+0.01*float64(expected) {
+		t.Errorf("mean signature of %.0f bytes, expected %d", mean, expected)
+	}
+	if 100*expected > 56*plain {
+		t.Errorf("expected compact signature of %d bytes, original format is %d", expected, plain)
+	}
+	if mqom := crypto.MQAT_GF256_MQOM.TokenSize(); 3*mqom > crypto.MQAT_GF256.TokenSize() {
+		t.Errorf("MQOM tokens of %d bytes, MQDSS tokens of %d", mqom, crypto.MQAT_GF256.TokenSize())
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"mqat/crypto"
+	"testing"
+	"time"
+)
+
+func TestMQOM(t *testing.T) {
+	for name, F := range fields {
+		for _, k := range []int{1, 2} {
+			mqom := crypto.NewMQOMOver(F, 8, 28, 8, 4, crypto.WithExtension(k), crypto.Insecure())
+			sk, pk := mqom.KeyGen()
+			msg := []byte("message")
+			sig := mqom.Sign(msg, sk)
+			if len(sig) != mqom.SignatureSize() {
+				t.Errorf("%s^%d: signature of %d bytes, expected %d", name, k, len(sig), mqom.SignatureSize())
+			}
+			if !mqom.Verify(msg, sig, pk) {
+				t.Errorf("%s^%d: signature does not verify", name, k)
+				continue
+			}
+			if mqom.Verify([]byte("other message"), sig, pk) {
+				t.Errorf("%s^%d: signature verifies for another message", name, k)
+			}
+			for _, pos := range []int{0, 40, 70, 130, len(sig) / 2, len(sig) - 1} {
+				bad := append([]byte{}, sig...)
+				bad[pos] ^= 1
+				if mqom.Verify(msg, bad, pk) {
+					t.Errorf("%s^%d: signature modified at byte %d verifies", name, k, pos)
+				}
+			}
+			if mqom.Verify(msg, sig[:len(sig)-1], pk) || mqom.Verify(msg, append(sig, 0), pk) {
+				t.Errorf("%s^%d: signature of the wrong length verifies", name, k)
+			}
+			// a wrong witness must not give a valid proof
+			sk.S = append([]uint8{}, sk.S...)
+			sk.S[0] = F.Add(sk.S[0], 1)
+			if mqom.Verify(msg, mqom.Sign(msg, sk), pk) {
+				t.Errorf("%s^%d: proof for a wrong witness verifies", name, k)
+			}
+		}
+	}
+}
+
+// TestMQOMVersusMQDSS compares the token size and verification time of the
+// MQDSS and MQOM parameter sets.
+func TestMQOMVersusMQDSS(t *testing.T) {
+	sizes := map[string]int{}
+	for _, p := range []crypto.ParameterSet{crypto.MQAT_GF256, crypto.MQAT_GF256_MQOM} {
+		mqat := p.New()
+		sk, pk := mqat.KeyGen()
+		tok, z_star, query := mqat.User0(pk)
+		token := mqat.User1(pk, tok, z_star, mqat.Sign0(sk, query))
+		if token == nil {
+			t.Fatalf("%s: could not finalize token", p.Name)
+		}
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			if !mqat.Verify(pk, token) {
+				t.Fatalf("%s: token does not verify", p.Name)
+			}
+		}
+		sizes[p.Name] = len(token.Token) + len(token.MQDSSSignature)
+		t.Logf("%s: %d-byte tokens, verified in %v", p.Name, sizes[p.Name], time.Since(start)/5)
+	}
+	if sizes[crypto.MQAT_GF256_MQOM.Name] >= sizes[crypto.MQAT_GF256.Name] {
+		t.Errorf("MQOM tokens of %d bytes, MQDSS tokens are %d", sizes[crypto.MQAT_GF256_MQOM.Name], sizes[crypto.MQAT_GF256.Name])
+	}
+}
@@ -0,0 +1,303 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mqat/crypto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var nistUOVSets = []crypto.UOVParams{crypto.UOV_Is, crypto.UOV_Ip, crypto.UOV_III, crypto.UOV_V}
+
+var nistUOVVariants = []crypto.UOVVariant{crypto.UOVClassic, crypto.UOVPkc, crypto.UOVPkcSkc}
+
+func TestNISTUOVSizes(t *testing.T) {
+	// public key, secret key, compressed public key and signature sizes of
+	// the specification
+	sizes := map[string][4]int{
+		"uov-Is":  {412160, 348704, 66576, 96},
+		"uov-Ip":  {278432, 237896, 43576, 128},
+		"uov-III": {1225440, 1044320, 189232, 200},
+		"uov-V":   {2869440, 2436704, 446992, 260},
+	}
+	for _, p := range nistUOVSets {
+		want := sizes[p.Name]
+		classic, pkc, skc := p.New(crypto.UOVClassic), p.New(crypto.UOVPkc), p.New(crypto.UOVPkcSkc)
+		got := [4]int{classic.PublicKeySize(), classic.SecretKeySize(), pkc.PublicKeySize(), classic.SignatureSize()}
+		if got != want || pkc.SecretKeySize() != want[1] || skc.PublicKeySize() != want[2] || skc.SecretKeySize() != 32 {
+			t.Errorf("%s: sizes %v, expected %v", p.Name, got, want)
+		}
+	}
+}
+
+func TestNISTUOV(t *testing.T) {
+	msg := []byte("message")
+	for _, p := range []crypto.UOVParams{crypto.UOV_Is, crypto.UOV_Ip} {
+		keys := map[crypto.UOVVariant][2][]byte{}
+		for _, variant := range nistUOVVariants {
+			// the same secret seed for every format
+			uov := p.New(variant)
+			uov.Rand = bytes.NewReader(make([]byte, 32))
+			sk, pk, err := uov.KeyGen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sk) != uov.SecretKeySize() || len(pk) != uov.PublicKeySize() {
+				t.Errorf("%s %s: keys of %d and %d bytes", p.Name, variant, len(sk), len(pk))
+			}
+			keys[variant] = [2][]byte{sk, pk}
+			uov.Rand = nil
+			sig, err := uov.Sign(msg, sk)
+			if err != nil {
+				t.Fatalf("%s %s: %v", p.Name, variant, err)
+			}
+			if !uov.Verify(msg, sig, pk) {
+				t.Errorf("%s %s: signature does not verify", p.Name, variant)
+			}
+			if uov.Verify([]byte("other message"), sig, pk) {
+				t.Errorf("%s %s: signature verifies another message", p.Name, variant)
+			}
+			sig[len(sig)-1] ^= 1
+			if uov.Verify(msg, sig, pk) {
+				t.Errorf("%s %s: signature verifies with another salt", p.Name, variant)
+			}
+		}
+		classic, pkc, skc := keys[crypto.UOVClassic], keys[crypto.UOVPkc], keys[crypto.UOVPkcSkc]
+		if !bytes.Equal(classic[0], pkc[0]) || !bytes.Equal(pkc[1], skc[1]) || !bytes.Equal(skc[0], classic[0][:32]) {
+			t.Errorf("%s: formats of the same key disagree", p.Name)
+		}
+		// a signature by the compressed secret key verifies under the
+		// classic public key
+		sig, _ := p.New(crypto.UOVPkcSkc).Sign(msg, skc[0])
+		if !p.New(crypto.UOVClassic).Verify(msg, sig, classic[1]) {
+			t.Errorf("%s: signature does not verify under the expanded key", p.Name)
+		}
+	}
+}
+
+func TestMQATNISTUOV(t *testing.T) {
+	mqat := crypto.MQAT_GF16_NISTUOV.New()
+	if mqat == nil {
+		t.Fatal("could not instantiate MQAT with uov-Is")
+	}
+	sk, pk := mqat.KeyGen()
+	tok, z_star, query := mqat.User0(pk)
+	resp := mqat.Sign0(sk, query)
+	token := mqat.User1(pk, tok, z_star, resp)
+	if token == nil || !mqat.Verify(pk, token) {
+		t.Fatal("token does not verify")
+	}
+	// uov-Ip gives the tokens 44 equations in 156 variables
+	ip := crypto.MQAT_GF256
+	ip.M, ip.N, ip.NISTUOV = crypto.UOV_Ip.M, crypto.UOV_Ip.N, crypto.UOVPkc
+	if ip.New() != nil {
+		t.Error("accepted uov-Ip below 128 bits")
+	}
+	if ip.New(crypto.Insecure()) == nil {
+		t.Error("uov-Ip not found for its dimensions")
+	}
+	if crypto.MQAT_GF256.New(crypto.WithNISTUOV(crypto.UOVPkc)) != nil {
+		t.Error("found a NIST UOV set for the default dimensions")
+	}
+	if crypto.MQAT_GF16_NISTUOV.New(crypto.WithMAYO(16, 4)) != nil {
+		t.Error("MQAT accepted both NIST UOV and MAYO")
+	}
+}
+
+// The seed of the first entry of every NIST KAT file, the DRBG being
+// seeded with the bytes 0 to 47.
+func TestKATRNG(t *testing.T) {
+	seed, _ := katEntry0()
+	want, _ := hex.DecodeString("061550234d158c5ec95595fe04ef7a25767f2e24cc2bc479d09d86dc9abcfde7056a8c266f9ef97ed08541dbd2e1ffa1")
+	if !bytes.Equal(seed, want) {
+		t.Errorf("KAT seed %x, expected %x", seed, want)
+	}
+}
+
+// SHA-256 of pk, sk and sm of the count-0 entry of each set and format,
+// computed with this implementation by replaying PQCgenKAT_sign (see
+// katEntry0). They are not copied from the submission's KAT files, which
+// TestNISTUOVKAT compares against when present: they keep CI able to catch
+// any change to the encodings, the expansion or the signing without them.
+var nistUOVKATDigests = map[string][3]string{
+	"uov-Is-classic":  {"23fd21df1a5db461a200e1987c3429e679122c5a27fe0085e0efbb2fbfaa0b71", "6e886fff4c53143b590b3a219b1d6f3e8b1060861c6ae1e1ffbf4a6fd1b13893", "06dcc8c52674d8073c09faba48058defe4ee2a0e58d016b2531834f46f4f08e7"},
+	"uov-Is-pkc":      {"e0f7c8851e0542040e9265964b3079dda4ed6400027172bc9d39bd9625bd85cb", "6e886fff4c53143b590b3a219b1d6f3e8b1060861c6ae1e1ffbf4a6fd1b13893", "06dcc8c52674d8073c09faba48058defe4ee2a0e58d016b2531834f46f4f08e7"},
+	"uov-Is-pkc+skc":  {"e0f7c8851e0542040e9265964b3079dda4ed6400027172bc9d39bd9625bd85cb", "94a4a571437d00113e132969b4dcf24ffcd4593be3c3bcc7df6c772da7f40615", "06dcc8c52674d8073c09faba48058defe4ee2a0e58d016b2531834f46f4f08e7"},
+	"uov-Ip-classic":  {"0fac013d1f6ea1c280ac853d41b30bfbe24b3a481d1c5aeca69d0c55760c75b2", "54fdbdc9f354a87cd93397505ad3baefd6106b3e406efa14c4453df4d57092f8", "faaa448feda3f0ff6d135d41b968482d1a89bff6287b5553561ccccbc0080076"},
+	"uov-Ip-pkc":      {"b8a012f58b0f92fd07758b663c939a4aed179fcfce5d958e2abf688b9ef85291", "54fdbdc9f354a87cd93397505ad3baefd6106b3e406efa14c4453df4d57092f8", "faaa448feda3f0ff6d135d41b968482d1a89bff6287b5553561ccccbc0080076"},
+	"uov-Ip-pkc+skc":  {"b8a012f58b0f92fd07758b663c939a4aed179fcfce5d958e2abf688b9ef85291", "94a4a571437d00113e132969b4dcf24ffcd4593be3c3bcc7df6c772da7f40615", "faaa448feda3f0ff6d135d41b968482d1a89bff6287b5553561ccccbc0080076"},
+	"uov-III-classic": {"a828c1e236638d66532d90aabb5e48ef0886776bee2a86f6d70a86b83406e50b", "3aa37cd838e63d21610433e2ecf48ebce4ffbdc8b65fb6b96257587f7685c1b1", "8890ca460425bde60c41e29494625717b6aaabcea6619e665bc4f503ffcd25f1"},
+	"uov-III-pkc":     {"7958d7bdc0a9647d4f4e7a0bd271d68547437f7806146a800d7cbc32f9c82374", "3aa37cd838e63d21610433e2ecf48ebce4ffbdc8b65fb6b96257587f7685c1b1", "8890ca460425bde60c41e29494625717b6aaabcea6619e665bc4f503ffcd25f1"},
+	"uov-III-pkc+skc": {"7958d7bdc0a9647d4f4e7a0bd271d68547437f7806146a800d7cbc32f9c82374", "94a4a571437d00113e132969b4dcf24ffcd4593be3c3bcc7df6c772da7f40615", "8890ca460425bde60c41e29494625717b6aaabcea6619e665bc4f503ffcd25f1"},
+	"uov-V-classic":   {"d6503975104055351c3245dc57bdc0388158761db7b689bd8ece64b94f8f04e3", "fe78b58d830febdff16c585bdcb08d4ab77177344528fde518e329b41e237a8a", "3514a7f904c04ae64e243530e394012352c10b3a5cde18b8afe274c9e6abe279"},
+	"uov-V-pkc":       {"7175fe7c8af7d8dfabcff9c4845a63a2067930ae1438ee7205bab235e47e874b", "fe78b58d830febdff16c585bdcb08d4ab77177344528fde518e329b41e237a8a", "3514a7f904c04ae64e243530e394012352c10b3a5cde18b8afe274c9e6abe279"},
+	"uov-V-pkc+skc":   {"7175fe7c8af7d8dfabcff9c4845a63a2067930ae1438ee7205bab235e47e874b", "94a4a571437d00113e132969b4dcf24ffcd4593be3c3bcc7df6c772da7f40615", "3514a7f904c04ae64e243530e394012352c10b3a5cde18b8afe274c9e6abe279"},
+}
+
+func TestNISTUOVKATDigests(t *testing.T) {
+	seed, msg := katEntry0()
+	for _, p := range nistUOVSets {
+		if testing.Short() && p.Name != crypto.UOV_Is.Name && p.Name != crypto.UOV_Ip.Name {
+			continue
+		}
+		for _, variant := range nistUOVVariants {
+			name := p.Name + "-" + variant.String()
+			uov := p.New(variant)
+			uov.Rand = newKATRNG(seed)
+			sk, pk, err := uov.KeyGen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sig, err := uov.Sign(msg, sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := [3]string{sha256hex(pk), sha256hex(sk), sha256hex(append(bytes.Clone(msg), sig...))}
+			if want := nistUOVKATDigests[name]; got != want {
+				t.Errorf("%s: digests %v, expected %v", name, got, want)
+			}
+		}
+	}
+}
+
+// TestNISTUOVKAT checks the first entries of the KAT files of the
+// submission, copied as testdata/uov/<set>-<format>.rsp, e.g.
+// uov-Ip-pkc+skc.rsp, and skips the missing ones.
+func TestNISTUOVKAT(t *testing.T) {
+	found := false
+	for _, p := range nistUOVSets {
+		for _, variant := range nistUOVVariants {
+			name := filepath.Join("testdata", "uov", p.Name+"-"+variant.String()+".rsp")
+			entries, err := readKAT(name, 3)
+			if err != nil {
+				continue
+			}
+			found = true
+			uov := p.New(variant)
+			for _, e := range entries {
+				uov.Rand = newKATRNG(e["seed"])
+				sk, pk, err := uov.KeyGen()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(pk, e["pk"]) || !bytes.Equal(sk, e["sk"]) {
+					t.Errorf("%s: keys of entry %s differ", name, e["count"])
+					continue
+				}
+				sig, err := uov.Sign(e["msg"], sk)
+				if err != nil || !bytes.Equal(append(bytes.Clone(e["msg"]), sig...), e["sm"]) {
+					t.Errorf("%s: signature of entry %s differs", name, e["count"])
+				}
+			}
+		}
+	}
+	if !found {
+		t.Skip("no KAT files in testdata/uov, see the README of the submission to generate them")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////////////
+
+// katEntry0 returns the seed and the message of the first entry of a KAT
+// file: PQCgenKAT_sign seeds its DRBG with the bytes 0 to 47 and draws the
+// seed, then a message of 33 bytes.
+func katEntry0() ([]byte, []byte) {
+	entropy := make([]byte, 48)
+	for i := range entropy {
+		entropy[i] = byte(i)
+	}
+	rng := newKATRNG(entropy)
+	seed, msg := make([]byte, 48), make([]byte, 33)
+	rng.Read(seed)
+	rng.Read(msg)
+	return seed, msg
+}
+
+func sha256hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// readKAT reads the first n entries of a NIST KAT response file.
+func readKAT(name string, n int) ([]map[string][]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []map[string][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " = ")
+		if !ok {
+			continue
+		}
+		if key == "count" {
+			if len(entries) == n {
+				break
+			}
+			entries = append(entries, map[string][]byte{"count": []byte(value)})
+			continue
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		if entries[len(entries)-1][key], err = hex.DecodeString(value); err != nil {
+			return nil, err
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// katRNG is the AES-256 CTR_DRBG of the NIST KAT generator, without
+// derivation function.
+type katRNG struct {
+	key, v []byte
+}
+
+func newKATRNG(seed []byte) *katRNG {
+	r := &katRNG{key: make([]byte, 32), v: make([]byte, 16)}
+	r.update(seed)
+	return r
+}
+
+func (r *katRNG) block() []byte {
+	for j := 15; j >= 0; j-- {
+		r.v[j]++
+		if r.v[j] != 0 {
+			break
+		}
+	}
+	c, _ := aes.NewCipher(r.key)
+	out := make([]byte, 16)
+	c.Encrypt(out, r.v)
+	return out
+}
+
+func (r *katRNG) update(data []byte) {
+	temp := make([]byte, 0, 48)
+	for i := 0; i < 3; i++ {
+		temp = append(temp, r.block()...)
+	}
+	for i := range data {
+		temp[i] ^= data[i]
+	}
+	r.key, r.v = temp[:32], temp[32:]
+}
+
+func (r *katRNG) Read(p []byte) (int, error) {
+	for i := 0; i < len(p); i += 16 {
+		copy(p[i:], r.block())
+	}
+	r.update(nil)
+	return len(p), nil
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+	"time"
+)
+
+func TestMQDSSPrecomputed(t *testing.T) {
+	for name, F := range fields {
+		mqdss := crypto.NewMQDSSOver(F, 8, 28, 32, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN,
+			crypto.WithExtension(2), crypto.Insecure())
+		sk, pk := mqdss.KeyGen()
+		msg := []byte("message")
+		for _, witness := range []*crypto.MQDSSSecretKey{sk, nil} {
+			pre := mqdss.Precompute(pk, witness)
+			sig := mqdss.SignWithPrecomputed(msg, sk, pre)
+			if !mqdss.Verify(msg, sig, pk) {
+				t.Errorf("%s, witness %v: signature does not verify", name, witness != nil)
+			}
+			if mqdss.SignWithPrecomputed([]byte("other message"), sk, pre) != nil {
+				t.Errorf("%s, witness %v: precomputed material used twice", name, witness != nil)
+			}
+		}
+		other, _ := mqdss.KeyGen()
+		if mqdss.SignWithPrecomputed(msg, other, mqdss.Precompute(pk, nil)) != nil {
+			t.Errorf("%s: material precomputed for another key accepted", name)
+		}
+		if mqdss.Precompute(pk, other) != nil {
+			t.Errorf("%s: precomputed for a witness of another key", name)
+		}
+	}
+	compact := crypto.NewMQDSSOver(math.GF256, 8, 28, 32, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN,
+		crypto.Compact(), crypto.Insecure())
+	_, pk := compact.KeyGen()
+	if compact.Precompute(pk, nil) != nil {
+		t.Error("precomputation accepted in the compact format")
+	}
+}
+
+func TestMQATPrecomputed(t *testing.T) {
+	mqat := crypto.MQAT_GF256.New()
+	sk, pk := mqat.KeyGen()
+	tok, z_star, query := mqat.User0(pk)
+	start := time.Now()
+	pre := mqat.PrecomputeUser1(pk, tok)
+	offline := time.Since(start)
+	resp := mqat.Sign0(sk, query)
+
+	start = time.Now()
+	token := mqat.User1WithPrecomputed(pk, tok, z_star, resp, pre)
+	online := time.Since(start)
+	if token == nil || !mqat.Verify(pk, token) {
+		t.Fatal("token does not verify")
+	}
+	if mqat.User1WithPrecomputed(pk, tok, z_star, resp, pre) != nil {
+		t.Error("precomputed material used twice")
+	}
+	start = time.Now()
+	mqat.User1(pk, tok, z_star, resp)
+	t.Logf("offline %v, online %v, User1 without precomputation %v", offline, online, time.Since(start))
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func TestPRGStreams(t *testing.T) {
+	seed := bytes.Repeat([]byte{7}, 16)
+	want := map[crypto.PRG][]byte{
+		crypto.SHAKE128:  make([]byte, 1000),
+		crypto.SHAKE256:  make([]byte, 1000),
+		crypto.AES128CTR: make([]byte, 1000),
+	}
+	sha3.ShakeSum128(want[crypto.SHAKE128], seed)
+	sha3.ShakeSum256(want[crypto.SHAKE256], seed)
+	block, _ := aes.NewCipher(seed)
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(want[crypto.AES128CTR], want[crypto.AES128CTR])
+
+	for prg, w := range want {
+		// reads of odd sizes must not lose stream bytes
+		r := prg.Expand(seed)
+		got := make([]byte, 0, len(w))
+		for _, l := range []int{1, 15, 17, 300, 667} {
+			buf := make([]byte, l)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				t.Fatalf("%s: %v", prg.Name(), err)
+			}
+			got = append(got, buf...)
+		}
+		if !bytes.Equal(got, w) {
+			t.Errorf("%s: unexpected stream", prg.Name())
+		}
+		if crypto.PRGByName(prg.Name()) != prg {
+			t.Errorf("%s: not found by name", prg.Name())
+		}
+	}
+	if _, err := crypto.AES128CTR.Expand(seed[:15]).Read(make([]byte, 1)); err == nil {
+		t.Error("AES-128-CTR accepted a 15-byte seed")
+	}
+}
+
+func TestMQATWithPRGs(t *testing.T) {
+	for _, prg := range []crypto.PRG{crypto.SHAKE128, crypto.SHAKE256, crypto.AES128CTR} {
+		mqat := crypto.NewMQATOver(
+			math.GF256,
+			20, 8,
+			constants.SALT_LEN,
+			constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+			constants.RANDOM_SYS_SEED_LEN,
+			16, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN,
+			crypto.WithPRG(prg), crypto.Insecure(),
+		)
+		sk, pk := mqat.KeyGen()
+		tok, z_star, query := mqat.User0(pk)
+		token := mqat.User1(pk, tok, z_star, mqat.Sign0(sk, query))
+		if token == nil || !mqat.Verify(pk, token) {
+			t.Errorf("%s: token does not verify", prg.Name())
+		}
+	}
+}
+
+// BenchmarkPRG expands the public matrices of UOV at the default parameters.
+func BenchmarkPRG(b *testing.B) {
+	v := constants.N - constants.M
+	size := constants.M*v*(v+1)/2 + constants.M*constants.M*v
+	seed := make([]byte, 16)
+	for _, prg := range []crypto.PRG{crypto.SHAKE128, crypto.SHAKE256, crypto.AES128CTR} {
+		b.Run(prg.Name(), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				math.GF256.Sample(prg.Expand(seed), size)
+			}
+		})
+	}
+}
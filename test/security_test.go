@@ -0,0 +1,164 @@
+package test
+
+import (
+	gomath "math"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+)
+
+func TestSecurityEstimate(t *testing.T) {
+	e := crypto.EstimateMQAT(constants.Q, constants.M, constants.N, constants.MQDSS_ROUNDS)
+	t.Logf("default parameters: %.1f bits (%+v)", e.Bits(), e)
+	if e.Bits() < constants.LAMBDA-1 {
+		t.Errorf("default parameters estimated at %.1f bits", e.Bits())
+	}
+
+	// UOV with n < 2m falls to Kipnis-Shamir in polynomial time
+	if bits := crypto.EstimateUOV(256, 44, 80).Bits(); bits > 40 {
+		t.Errorf("UOV with n < 2m estimated at %.1f bits", bits)
+	}
+	// a single round of MQDSS is forged with probability about 1/2
+	if bits := crypto.EstimateMQDSS(256, 44, 156, 1).Bits(); bits > 2 {
+		t.Errorf("MQDSS with one round estimated at %.1f bits", bits)
+	}
+	if crypto.FiatShamirBits(255, 100) >= crypto.FiatShamirBits(255, 156) {
+		t.Error("soundness does not grow with the number of rounds")
+	}
+	if crypto.DirectAttackBits(256, 30, 30) >= crypto.DirectAttackBits(256, 44, 44) {
+		t.Error("direct attack cost does not grow with the number of equations")
+	}
+}
+
+func TestMQDSSRounds(t *testing.T) {
+	if r := crypto.MQDSSRounds(constants.Q, constants.LAMBDA); r != constants.MQDSS_ROUNDS {
+		t.Errorf("derived %d rounds, MQDSS_ROUNDS is %d", r, constants.MQDSS_ROUNDS)
+	}
+	for name, F := range fields {
+		q := F.Order()
+		r := crypto.MQDSSRounds(q, constants.LAMBDA)
+		bits := crypto.FiatShamirBits(q-1, r)
+		// the plain soundness bound is above the cost of the Kales-Zaverucha attack
+		plain := -float64(r) * gomath.Log2(crypto.SoundnessError(q-1))
+		t.Logf("%s: %d rounds, soundness error %.4f per round, %.1f bits (%.1f without grinding)",
+			name, r, crypto.SoundnessError(q-1), bits, plain)
+		if bits < constants.LAMBDA || plain < bits {
+			t.Errorf("%s: %d rounds only reach %.1f bits", name, r, bits)
+		}
+		if crypto.FiatShamirBits(q-1, r-1) >= constants.LAMBDA {
+			t.Errorf("%s: %d rounds are not the least to reach the target", name, r)
+		}
+	}
+	// smaller fields need more rounds than the GF(256) default
+	if crypto.NewMQDSSOver(math.GF16, 44, 156, constants.MQDSS_ROUNDS, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN) != nil {
+		t.Error("MQDSS over GF16 accepted with the GF256 round count")
+	}
+}
+
+func TestParameterSets(t *testing.T) {
+	for _, p := range []crypto.ParameterSet{crypto.MQAT_GF256, crypto.MQAT_GF256_EXT2, crypto.MQAT_GF256_COMPACT, crypto.MQAT_GF256_MQOM,
+		crypto.MQAT_GF16_NISTUOV, crypto.MQAT_GF256_MAYO} {
+		q := 1
+		for i := 0; i < p.Degree; i++ {
+			q *= p.Field.Order()
+		}
+		r := crypto.MQDSSRounds(q, constants.LAMBDA)
+		if p.LogParties > 0 {
+			r = crypto.MQOMRepetitions(q, p.LogParties, constants.LAMBDA)
+		}
+		if r != p.Rounds {
+			t.Errorf("%s: %d rounds, derived %d", p.Name, p.Rounds, r)
+		}
+		if bits := p.Security().Bits(); bits < constants.LAMBDA {
+			t.Errorf("%s: estimated at %.1f bits", p.Name, bits)
+		}
+		if p.New() == nil {
+			t.Errorf("%s: refused", p.Name)
+		}
+		t.Logf("%s: %d rounds, %d-byte tokens (%d on average)", p.Name, p.Rounds, p.TokenSize(), p.ExpectedTokenSize())
+	}
+}
+
+// Over GF256 the extension field saves rounds but not bytes.
+func TestExtensionTokenSize(t *testing.T) {
+	plain, ext2 := crypto.MQAT_GF256, crypto.MQAT_GF256_EXT2
+	if ext2.Rounds >= plain.Rounds {
+		t.Errorf("%s: %d rounds, %s: %d", ext2.Name, ext2.Rounds, plain.Name, plain.Rounds)
+	}
+	if ext2.TokenSize() <= plain.TokenSize() {
+		t.Errorf("%s tokens of %d bytes are no longer larger than the %d bytes of %s; update its description",
+			ext2.Name, ext2.TokenSize(), plain.TokenSize(), plain.Name)
+	}
+	t.Logf("%s: %d-byte tokens, %s: %d-byte tokens", plain.Name, plain.TokenSize(), ext2.Name, ext2.TokenSize())
+}
+
+func TestConstructorsRefuseInsecureParameters(t *testing.T) {
+	if crypto.NewUOV(constants.M, constants.N, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN) == nil {
+		t.Error("default UOV parameters refused")
+	}
+	if crypto.NewUOV(44, 80, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN) != nil {
+		t.Error("UOV with n < 2m accepted")
+	}
+	if crypto.NewMQDSS(44, 156, 1, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN) != nil {
+		t.Error("MQDSS with one round accepted")
+	}
+	if crypto.NewMQDSS(44, 156, 1, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, crypto.Insecure()) == nil {
+		t.Error("insecure MQDSS refused despite the flag")
+	}
+	if crypto.NewUOVOver(math.GF256, constants.M, constants.N, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		crypto.WithSecurityTarget(192)) != nil {
+		t.Error("UOV accepted above its estimated security")
+	}
+
+	newMQAT := func(m, n, rounds int, opts ...crypto.Option) *crypto.MQAT {
+		return crypto.NewMQAT(
+			n, m,
+			constants.SALT_LEN,
+			constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+			constants.RANDOM_SYS_SEED_LEN,
+			rounds, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN,
+			opts...,
+		)
+	}
+	if newMQAT(constants.M, constants.N, constants.MQDSS_ROUNDS) == nil {
+		t.Error("default MQAT parameters refused")
+	}
+	if newMQAT(8, 20, 16) != nil {
+		t.Error("toy MQAT parameters accepted")
+	}
+	if newMQAT(8, 20, 16, crypto.Insecure()) == nil {
+		t.Error("toy MQAT parameters refused despite the flag")
+	}
+}
+
+func TestConstructorsRefuseInapplicableOptions(t *testing.T) {
+	uov := func(opts ...crypto.Option) *crypto.UOV {
+		return crypto.NewUOV(8, 24, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, append(opts, crypto.Insecure())...)
+	}
+	mqdss := func(opts ...crypto.Option) *crypto.MQDSS {
+		return crypto.NewMQDSS(16, 32, 16, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, append(opts, crypto.Insecure())...)
+	}
+	mqat := func(opts ...crypto.Option) *crypto.MQAT {
+		return crypto.NewMQATOver(math.GF256, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+			constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, append(opts, crypto.Insecure())...)
+	}
+	if uov(crypto.WithPRG(crypto.AES128CTR)) == nil || mqdss(crypto.Compact(), crypto.WithExtension(2)) == nil ||
+		mqat(crypto.WithMQOM(4), crypto.WithExtension(2)) == nil {
+		t.Fatal("applicable options refused")
+	}
+	for name, ok := range map[string]bool{
+		"UOV with Compact":           uov(crypto.Compact()) == nil,
+		"UOV with WithSigningMode":   uov(crypto.WithSigningMode(crypto.Deterministic)) == nil,
+		"MAYO with WithExtension":    crypto.NewMAYO(16, 20, 4, 5, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.WithExtension(2), crypto.Insecure()) == nil,
+		"MQDSS with WithMAYO":        mqdss(crypto.WithMAYO(4, 5)) == nil,
+		"MQDSS with WithMQOM":        mqdss(crypto.WithMQOM(4)) == nil,
+		"MQOM with Compact":          crypto.NewMQOM(16, 32, 8, 4, crypto.Compact(), crypto.Insecure()) == nil,
+		"MQAT with Interactive":      mqat(crypto.Interactive()) == nil,
+		"MQAT with MQOM and Compact": mqat(crypto.WithMQOM(4), crypto.Compact()) == nil,
+	} {
+		if !ok {
+			t.Errorf("%s accepted", name)
+		}
+	}
+}
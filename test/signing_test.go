@@ -0,0 +1,68 @@
+package test
+
+import (
+	"bytes"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+)
+
+type signer interface {
+	SignWith(message []uint8, sk *crypto.MQDSSSecretKey, mode crypto.SigningMode) []byte
+	Verify(message []uint8, sig []byte, pk *crypto.MQDSSPublicKey) bool
+}
+
+func TestSigningModes(t *testing.T) {
+	mqdss := crypto.NewMQDSSOver(math.GF256, 8, 28, 32, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN,
+		crypto.Insecure())
+	compact := crypto.NewMQDSSOver(math.GF256, 8, 28, 32, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN,
+		crypto.Compact(), crypto.Insecure())
+	mqom := crypto.NewMQOMOver(math.GF256, 8, 28, 8, 4, crypto.Insecure())
+	sk, pk := mqdss.KeyGen()
+	msg := []byte("message")
+	for name, s := range map[string]signer{"MQDSS": mqdss, "compact MQDSS": compact, "MQOM": mqom} {
+		for _, mode := range []crypto.SigningMode{crypto.Hedged, crypto.Deterministic, crypto.Randomized} {
+			sig1 := s.SignWith(msg, sk, mode)
+			sig2 := s.SignWith(msg, sk, mode)
+			if !s.Verify(msg, sig1, pk) || !s.Verify(msg, sig2, pk) {
+				t.Errorf("%s, %s: signature does not verify", name, mode)
+			}
+			if equal := bytes.Equal(sig1, sig2); equal != (mode == crypto.Deterministic) {
+				t.Errorf("%s, %s: repeated signatures equal: %v", name, mode, equal)
+			}
+		}
+		// the randomness must depend on the message and on the witness
+		other := s.SignWith([]byte("other message"), sk, crypto.Deterministic)
+		if bytes.Equal(other[64:], s.SignWith(msg, sk, crypto.Deterministic)[64:]) {
+			t.Errorf("%s: deterministic randomness independent of the message", name)
+		}
+		if s.SignWith(msg, sk, crypto.SigningMode(-1)) != nil {
+			t.Errorf("%s: signed with an unknown mode", name)
+		}
+	}
+}
+
+func TestMQATSigningModes(t *testing.T) {
+	mqat := crypto.NewMQATOver(
+		math.GF256,
+		20, 8,
+		constants.SALT_LEN,
+		constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN,
+		16, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN,
+		crypto.WithSigningMode(crypto.Deterministic), crypto.Insecure(),
+	)
+	sk, pk := mqat.KeyGen()
+	tok, z_star, query := mqat.User0(pk)
+	resp := mqat.Sign0(sk, query)
+	token1 := mqat.User1(pk, tok, z_star, resp)
+	token2 := mqat.User1(pk, tok, z_star, resp)
+	if !mqat.Verify(pk, token1) || !bytes.Equal(token1.MQDSSSignature, token2.MQDSSSignature) {
+		t.Error("deterministic tokens differ or do not verify")
+	}
+	token3 := mqat.User1With(pk, tok, z_star, resp, crypto.Hedged)
+	if !mqat.Verify(pk, token3) || bytes.Equal(token1.MQDSSSignature, token3.MQDSSSignature) {
+		t.Error("hedged token equals the deterministic one or does not verify")
+	}
+}
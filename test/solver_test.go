@@ -0,0 +1,138 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+)
+
+func plantedChallenge(t testing.TB, F math.Field, m, n int, seed byte) (*math.Challenge, []uint8) {
+	sys, err := math.NewMQSystemFromSeed(F, m, n, []byte{seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := sampleOver(F, n, []byte{seed})
+	c, err := math.NewChallenge(sys, sys.Eval(x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, x
+}
+
+func TestExhaustiveSearch(t *testing.T) {
+	c, x := plantedChallenge(t, math.GF16, 6, 4, 1)
+	found := false
+	for _, sol := range math.ExhaustiveSearch(c, 0) {
+		found = found || bytes.Equal(sol, x)
+	}
+	if !found {
+		t.Error("planted solution was not found")
+	}
+
+	// the whole of GF(256)^2 is enumerated exactly once
+	sys, _ := math.NewMQSystem(math.GF256, 1, 2, make([]uint8, 3))
+	zero, _ := math.NewChallenge(sys, nil)
+	if sols := math.ExhaustiveSearch(zero, 0); len(sols) != 256*256 {
+		t.Errorf("enumerated %d assignments, expected %d", len(sols), 256*256)
+	}
+}
+
+func TestXL(t *testing.T) {
+	for _, tc := range []struct{ m, n, D int }{
+		{15, 4, 2}, // linearization
+		{10, 5, 4},
+	} {
+		for name, F := range fields {
+			c, _ := plantedChallenge(t, F, tc.m, tc.n, 2)
+			sol, err := math.XL(c, tc.D)
+			if err != nil {
+				t.Errorf("%s, m=%d, n=%d, D=%d: %v", name, tc.m, tc.n, tc.D, err)
+				continue
+			}
+			// homogeneous instances are also solved by -x
+			for _, v := range c.System.Eval(append(sol, 1)) {
+				if v != 0 {
+					t.Errorf("%s, m=%d, n=%d, D=%d: wrong solution", name, tc.m, tc.n, tc.D)
+					break
+				}
+			}
+		}
+	}
+
+	c, _ := plantedChallenge(t, math.GF31, 4, 4, 3)
+	if _, err := math.XL(c, 2); err != math.ErrUnresolved {
+		t.Errorf("linearization of a square system returned %v", err)
+	}
+}
+
+func TestChallengeFix(t *testing.T) {
+	c, x := plantedChallenge(t, math.GF31, 6, 5, 4)
+	fixed, err := c.Fix(x[3:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed.Variables() != 3 {
+		t.Fatalf("fixed challenge has %d variables", fixed.Variables())
+	}
+	for _, v := range fixed.System.Eval(append(bytes.Clone(x[:3]), 1)) {
+		if v != 0 {
+			t.Error("planted solution does not solve the fixed challenge")
+			return
+		}
+	}
+}
+
+// The benchmarks below solve toy instances of the UOV forgery and MQAT token
+// problems with the hybrid approach: the surplus variables are fixed at
+// random and the remaining system is solved by exhaustive search when it is
+// square, or by XL at degree m when one more variable is fixed, the guess
+// then being right with probability 1/q. Run them with
+// `go test -bench Solve ./test` to observe how the cost grows with m.
+
+func BenchmarkSolveUOV(b *testing.B) {
+	for _, m := range []int{2, 3, 4} {
+		uov := crypto.NewUOVOver(math.GF16, m, 3*m, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+		_, pk := uov.KeyGen()
+		P, _ := uov.PublicMap(pk)
+		c, _ := math.NewChallenge(P, sampleOver(math.GF16, m, []byte{1}))
+		b.Run(fmt.Sprintf("GF16/m=%d/exhaustive", m), func(b *testing.B) { solveHybrid(b, c, m, 0) })
+		b.Run(fmt.Sprintf("GF16/m=%d/XL", m), func(b *testing.B) { solveHybrid(b, c, m-1, m) })
+	}
+}
+
+func BenchmarkSolveMQAT(b *testing.B) {
+	for _, m := range []int{2, 3, 4} {
+		mqat := crypto.NewMQATOver(
+			math.GF16,
+			3*m, m,
+			constants.SALT_LEN,
+			constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+			constants.RANDOM_SYS_SEED_LEN,
+			constants.MQDSS_ROUNDS, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN,
+			crypto.Insecure(),
+		)
+		_, pk := mqat.KeyGen()
+		c, _ := mqat.Challenge(pk, []byte("token"))
+		b.Run(fmt.Sprintf("GF16/m=%d/exhaustive", m), func(b *testing.B) { solveHybrid(b, c, m, 0) })
+		b.Run(fmt.Sprintf("GF16/m=%d/XL", m), func(b *testing.B) { solveHybrid(b, c, m-1, m) })
+	}
+}
+
+// solveHybrid fixes all but free variables of c at random and solves the
+// rest by exhaustive search, or by XL at degree D if D > 0.
+func solveHybrid(b *testing.B, c *math.Challenge, free, D int) {
+	solved := 0
+	for i := 0; i < b.N; i++ {
+		guess := sampleOver(math.GF16, c.Variables()-free, []byte{byte(i), byte(i >> 8)})
+		fixed, _ := c.Fix(guess)
+		if D == 0 {
+			solved += len(math.ExhaustiveSearch(fixed, 1))
+		} else if _, err := math.XL(fixed, D); err == nil {
+			solved++
+		}
+	}
+	b.ReportMetric(float64(solved)/float64(b.N), "solved/op")
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"bytes"
+	"mqat/math"
+	"testing"
+)
+
+func TestMQSystemEval(t *testing.T) {
+	m, n := 5, 12
+	for name, F := range fields {
+		sys, err := math.NewMQSystemFromSeed(F, m, n, []byte{1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		R := sampleOver(F, math.Flen(m, n), []byte{1})
+		if !bytes.Equal(R, sys.Coeffs) {
+			t.Errorf("%s: seed expansion differs from SHAKE128 sampling", name)
+			return
+		}
+		x := sampleOver(F, n, []byte{2})
+		if !bytes.Equal(sys.Eval(x), math.MQROver(F, R, x, m)) {
+			t.Errorf("%s: Eval differs from MQR", name)
+		}
+	}
+}
+
+func TestMQSystemFromUOV(t *testing.T) {
+	m, n := 4, 10
+	v := n - m
+	for name, F := range fields {
+		P1 := sampleOver(F, m*v*(v+1)/2, []byte{1})
+		P2 := sampleOver(F, m*v*m, []byte{2})
+		P3 := sampleOver(F, m*m*(m+1)/2, []byte{3})
+		sys, err := math.NewMQSystemFromUOV(F, m, n, P1, P2, P3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := sampleOver(F, n, []byte{4})
+		if !bytes.Equal(sys.Eval(x), math.MQPOver(F, P1, P2, P3, x, m)) {
+			t.Errorf("%s: Eval differs from MQP", name)
+		}
+		if _, err := math.NewMQSystemFromUOV(F, m, n, P1[1:], P2, P3); err == nil {
+			t.Errorf("%s: truncated P1 was accepted", name)
+		}
+	}
+}
+
+func TestMQSystemPolar(t *testing.T) {
+	m, n := 5, 12
+	for name, F := range fields {
+		sys, _ := math.NewMQSystemFromSeed(F, m, n, []byte{1})
+		x := sampleOver(F, n, []byte{2})
+		y := sampleOver(F, n, []byte{3})
+		xy := make([]uint8, n)
+		for i := range xy {
+			xy[i] = F.Add(x[i], y[i])
+		}
+		fx, fy, fxy := sys.Eval(x), sys.Eval(y), sys.Eval(xy)
+		g := sys.Polar(x, y)
+		for i := 0; i < m; i++ {
+			if g[i] != F.Sub(F.Sub(fxy[i], fx[i]), fy[i]) {
+				t.Errorf("%s: G(x, y) != F(x+y) - F(x) - F(y)", name)
+				return
+			}
+		}
+	}
+}
+
+func TestMQSystemCompose(t *testing.T) {
+	m, n := 4, 7
+	for name, F := range fields {
+		sys, _ := math.NewMQSystemFromSeed(F, m, n, []byte{1})
+		T := math.NewDenseMatrix(n, n-2, sampleOver(F, n*(n-2), []byte{2}))
+		S := math.NewDenseMatrix(m+1, m, sampleOver(F, (m+1)*m, []byte{3}))
+		comp, err := sys.Compose(S, T)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r, c := comp.Dims(); r != m+1 || c != n-2 {
+			t.Errorf("%s: composed system has shape (%d,%d)", name, r, c)
+			return
+		}
+		x := sampleOver(F, n-2, []byte{4})
+		Tx := math.MulMatOver(F, T, math.NewVector(x))
+		exp := math.MulMatOver(F, S, math.NewVector(sys.Eval(Tx.Data)))
+		if !bytes.Equal(comp.Eval(x), exp.Data) {
+			t.Errorf("%s: (S∘F∘T)(x) != S(F(T(x)))", name)
+		}
+
+		id, _ := sys.Compose(nil, nil)
+		if !id.Equal(sys) {
+			t.Errorf("%s: composing with identities changed the system", name)
+		}
+	}
+}
+
+func TestMQSystemConcat(t *testing.T) {
+	m, n := 6, 14
+	for name, F := range fields {
+		v := n - m
+		P1 := sampleOver(F, m*v*(v+1)/2, []byte{1})
+		P2 := sampleOver(F, m*v*m, []byte{2})
+		P3 := sampleOver(F, m*m*(m+1)/2, []byte{3})
+		R := sampleOver(F, math.Flen(m, m), []byte{4})
+		P, _ := math.NewMQSystemFromUOV(F, m, n, P1, P2, P3)
+		Rs, _ := math.NewMQSystem(F, m, m, R)
+		PR, err := P.Concat(Rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := sampleOver(F, n+m, []byte{5})
+		if !bytes.Equal(PR.Eval(x), math.MQOver(F, P1, P2, P3, R, x, m, n)) {
+			t.Errorf("%s: P‖R differs from MQ", name)
+		}
+	}
+}
+
+func TestMQSystemSerialization(t *testing.T) {
+	for name, F := range fields {
+		sys, _ := math.NewMQSystemFromSeed(F, 3, 9, []byte{name[2]})
+		data, err := sys.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dec math.MQSystem
+		if err := dec.UnmarshalBinary(data); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !dec.Equal(sys) {
+			t.Errorf("%s: decoded system differs", name)
+		}
+		if err := dec.UnmarshalBinary(data[:len(data)-1]); err == nil {
+			t.Errorf("%s: truncated encoding was accepted", name)
+		}
+	}
+	if _, err := math.NewMQSystem(math.GF31, 1, 1, []uint8{31}); err == nil {
+		t.Error("out of range coefficient was accepted")
+	}
+}
@@ -0,0 +1,53 @@
+package test
+
+import (
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"strings"
+	"testing"
+)
+
+func TestTranscript(t *testing.T) {
+	R, N := 32, 28
+	for _, compact := range []bool{false, true} {
+		opts := []crypto.Option{crypto.Insecure()}
+		if compact {
+			opts = append(opts, crypto.Compact())
+		}
+		mqdss := crypto.NewMQDSSOver(math.GF256, 8, N, R, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, opts...)
+		sk, pk := mqdss.KeyGen()
+		msg := []byte("message")
+
+		var signer, verifier crypto.Transcript
+		sig := mqdss.SignTraced(msg, sk, &signer)
+		if !mqdss.VerifyTraced(msg, sig, pk, &verifier) {
+			t.Fatalf("compact %v: signature does not verify", compact)
+		}
+		if len(signer.Rounds) != R || signer.Alphas == nil {
+			t.Fatalf("compact %v: incomplete transcript\n%s", compact, &signer)
+		}
+		if diff := crypto.DiffTranscripts(&signer, &verifier); diff != nil {
+			t.Errorf("compact %v: honest transcripts differ: %v", compact, diff)
+		}
+
+		mqdss.VerifyTraced([]byte("other message"), sig, pk, &verifier)
+		if diff := crypto.DiffTranscripts(&signer, &verifier); len(diff) == 0 || !strings.HasPrefix(diff[0], "D:") {
+			t.Errorf("compact %v: wrong message: %v", compact, diff)
+		}
+		if compact {
+			continue
+		}
+
+		// the opening of round i only changes the commitment it reconstructs
+		i := 5
+		sig[mqdss.SignatureSize()-(R-i)*(N+constants.HASH_BYTES)] ^= 1
+		if mqdss.VerifyTraced(msg, sig, pk, &verifier) {
+			t.Fatal("modified signature verifies")
+		}
+		diff := crypto.DiffTranscripts(&signer, &verifier)
+		if len(diff) != 2 || !strings.HasPrefix(diff[0], "round 5: c") || !strings.HasPrefix(diff[1], "recomputed sigma0") {
+			t.Errorf("modified opening: %v", diff)
+		}
+	}
+}
@@ -92,3 +92,101 @@ func TestUOVCorrectness(t *testing.T) {
 	res2 := math.MQP(pk.P1i, pk.P2i, pk.P3i, sig, m)
 	t.Log(len(res2), "P(x') =", res2)
 }
+
+func TestUOVMessages(t *testing.T) {
+	uov := crypto.NewUOV(constants.M, constants.N, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN)
+	sk, pk := uov.KeyGen()
+
+	for _, msg := range [][]byte{nil, []byte("issuer configuration"), bytes.Repeat([]byte{7}, 10000)} {
+		sig, salt := uov.SignMessage(msg, sk)
+		if sig == nil || len(salt) != constants.SALT_LEN/8 {
+			t.Fatalf("could not sign a message of %d bytes", len(msg))
+		}
+		if !uov.VerifyMessage(msg, sig, salt, pk) {
+			t.Errorf("signature of a message of %d bytes does not verify", len(msg))
+		}
+		if uov.VerifyMessage(append(bytes.Clone(msg), 0), sig, salt, pk) {
+			t.Errorf("signature of a message of %d bytes verifies a longer one", len(msg))
+		}
+		salt[0] ^= 1
+		if uov.VerifyMessage(msg, sig, salt, pk) {
+			t.Errorf("signature of a message of %d bytes verifies with another salt", len(msg))
+		}
+	}
+	if uov.Sign([]uint8("short"), sk) != nil {
+		t.Error("signed a target of the wrong length")
+	}
+}
+
+func TestUOVKeyValidation(t *testing.T) {
+	for name, F := range fields {
+		uov := crypto.NewUOVOver(F, 8, 20, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+		sk, pk := uov.KeyGen()
+		if err := uov.ValidateSecretKey(sk, pk); err != nil {
+			t.Errorf("%s: valid key pair rejected: %v", name, err)
+		}
+		derived, err := uov.PublicKeyFromSecret(sk)
+		if err != nil || !bytes.Equal(derived.Seed, pk.Seed) || !bytes.Equal(derived.P2i, pk.P2i) || !bytes.Equal(derived.P3i, pk.P3i) {
+			t.Errorf("%s: public key not rebuilt: %v", name, err)
+		}
+
+		other_sk, other_pk := uov.KeyGen()
+		if err := uov.ValidateSecretKey(sk, other_pk); err != crypto.ErrKeyMismatch {
+			t.Errorf("%s: mismatched public key: %v", name, err)
+		}
+		// the public seeds differ, so the P1 of sk is not that of pk
+		other_sk.PkSeed, other_sk.P1i = pk.Seed, sk.P1i
+		if err := uov.ValidateSecretKey(other_sk, pk); err != crypto.ErrKeyMismatch {
+			t.Errorf("%s: mismatched oil space: %v", name, err)
+		}
+
+		corrupt := *pk
+		corrupt.P3i = bytes.Clone(pk.P3i)
+		corrupt.P3i[3] = F.Add(corrupt.P3i[3], 1)
+		if err := uov.ValidateSecretKey(sk, &corrupt); err != crypto.ErrKeyMismatch {
+			t.Errorf("%s: corrupted P3: %v", name, err)
+		}
+		corrupt.P3i = pk.P3i[1:]
+		if err := uov.ValidatePublicKey(&corrupt); err != crypto.ErrUOVKey {
+			t.Errorf("%s: truncated P3: %v", name, err)
+		}
+		corrupt = *pk
+		corrupt.P1i = bytes.Clone(pk.P1i)
+		corrupt.P1i[0] = F.Add(corrupt.P1i[0], 1)
+		if err := uov.ValidatePublicKey(&corrupt); err != crypto.ErrUOVKey {
+			t.Errorf("%s: P1 not expanded from the seed: %v", name, err)
+		}
+		if F.Order() < 256 {
+			corrupt = *pk
+			corrupt.P3i = bytes.Clone(pk.P3i)
+			corrupt.P3i[0] = uint8(F.Order())
+			if err := uov.ValidatePublicKey(&corrupt); err != crypto.ErrUOVKey {
+				t.Errorf("%s: entry out of the field: %v", name, err)
+			}
+		}
+
+		corrupt_sk := *sk
+		corrupt_sk.Si = bytes.Clone(sk.Si)
+		corrupt_sk.Si[5] = F.Add(corrupt_sk.Si[5], 1)
+		if err := uov.ValidateSecretKey(&corrupt_sk, pk); err != crypto.ErrKeyMismatch {
+			t.Errorf("%s: corrupted Si: %v", name, err)
+		}
+		if _, err := uov.PublicKeyFromSecret(&corrupt_sk); err == nil {
+			t.Errorf("%s: public key rebuilt from a corrupted Si", name)
+		}
+	}
+}
+
+func TestMQATValidateKeys(t *testing.T) {
+	for _, p := range []crypto.ParameterSet{crypto.MQAT_GF256, crypto.MQAT_GF16_NISTUOV} {
+		mqat := p.New()
+		sk, pk := mqat.KeyGen()
+		if err := mqat.ValidateKeys(sk, pk); err != nil {
+			t.Errorf("%s: valid key pair rejected: %v", p.Name, err)
+		}
+		other_sk, _ := mqat.KeyGen()
+		if err := mqat.ValidateKeys(other_sk, pk); err != crypto.ErrKeyMismatch {
+			t.Errorf("%s: mismatched key pair: %v", p.Name, err)
+		}
+	}
+}
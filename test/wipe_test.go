@@ -0,0 +1,120 @@
+package test
+
+import (
+	"bytes"
+	constants "mqat/const"
+	"mqat/crypto"
+	"mqat/math"
+	"testing"
+)
+
+func zero(bufs ...[]uint8) bool {
+	for _, b := range bufs {
+		for _, a := range b {
+			if a != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestDestroy(t *testing.T) {
+	uov := crypto.NewUOV(16, 48, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	sk, pk := uov.KeyGen()
+	msg := crypto.Nrand128(uov.M, []byte{4})
+	sig := uov.Sign(msg, sk)
+	O, Si, P1i := sk.O, sk.Si, sk.P1i
+	sk.Destroy()
+	if !zero(O, Si, P1i) || sk.O != nil {
+		t.Error("UOV secret key not wiped")
+	}
+	// the public key outlives the secret key
+	if uov.ValidatePublicKey(pk) != nil || !uov.Verify(msg, sig, pk) {
+		t.Error("destroying the UOV secret key broke the public key")
+	}
+	if uov.Sign(msg, sk) != nil {
+		t.Error("UOV signed with a destroyed key")
+	}
+	if _, err := uov.SignChecked(msg, sk, pk); err == nil {
+		t.Error("UOV.SignChecked signed with a destroyed key")
+	}
+
+	mqdss := crypto.NewMQDSS(16, 32, 16, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, crypto.Insecure())
+	mqdss_sk, _ := mqdss.KeyGen()
+	S := mqdss_sk.S
+	mqdss_sk.Destroy()
+	if !zero(S) || mqdss.Sign([]byte{4}, mqdss_sk) != nil {
+		t.Error("MQDSS signed with a destroyed key")
+	}
+
+	for _, opts := range [][]crypto.Option{{crypto.Insecure()}, {crypto.WithMAYO(4, 5), crypto.Insecure()}} {
+		mqat := crypto.NewMQATOver(math.GF256, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+			constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, opts...)
+		mqat_sk, mqat_pk := mqat.KeyGen()
+		tok, z_star, query := mqat.User0(mqat_pk)
+		token := mqat.User1(mqat_pk, tok, z_star, mqat.Sign0(mqat_sk, query))
+		_, _, query = mqat.User0(mqat_pk)
+		mqat_sk.Destroy()
+		if token == nil || !mqat.Verify(mqat_pk, token) {
+			t.Error("destroying the MQAT secret key broke the public key")
+		}
+		if mqat.Sign0(mqat_sk, query) != nil {
+			t.Error("MQAT issued with a destroyed key")
+		}
+		if _, err := mqat.Sign0Checked(mqat_sk, mqat_pk, query); err == nil {
+			t.Error("MQAT.Sign0Checked issued with a destroyed key")
+		}
+		mqat_sk.Destroy()
+	}
+}
+
+// The schemes wipe their temporaries but neither their inputs nor what the
+// caller still needs.
+func TestWipe(t *testing.T) {
+	mqat := crypto.NewMQATOver(math.GF256, 24, 16, constants.SALT_LEN, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN,
+		constants.RANDOM_SYS_SEED_LEN, 8, constants.MQDSS_SK_SEED_LEN, constants.MQDSS_PK_SEED_LEN, crypto.Insecure())
+	sk, pk := mqat.KeyGen()
+	for i := 0; i < 2; i++ {
+		tok, z_star, query := mqat.User0(pk)
+		resp := mqat.Sign0(sk, query)
+		saved_z, saved_resp := bytes.Clone(z_star), bytes.Clone(resp)
+		token := mqat.User1(pk, tok, z_star, resp)
+		if !bytes.Equal(z_star, saved_z) || !bytes.Equal(resp, saved_resp) {
+			t.Fatal("User1 wiped its inputs")
+		}
+		crypto.Wipe(z_star, resp)
+		if !zero(z_star, resp) || token == nil || !mqat.Verify(pk, token) {
+			t.Fatal("token does not verify once the witness is wiped")
+		}
+	}
+
+	for _, compact := range []bool{false, true} {
+		opts := []crypto.Option{crypto.Insecure()}
+		if compact {
+			opts = append(opts, crypto.Compact())
+		}
+		mqdss := crypto.NewMQDSS(16, 32, 16, constants.MQDSS_PK_SEED_LEN, constants.MQDSS_SK_SEED_LEN, opts...)
+		mqdss_sk, mqdss_pk := mqdss.KeyGen()
+		S := bytes.Clone(mqdss_sk.S)
+		m := []byte{5}
+		if !mqdss.Verify(m, mqdss.Sign(m, mqdss_sk), mqdss_pk) || !bytes.Equal(S, mqdss_sk.S) {
+			t.Errorf("compact %v: signing wiped the key", compact)
+		}
+		if !mqdss.Verify(m, mqdss.Sign(m, mqdss_sk), mqdss_pk) {
+			t.Errorf("compact %v: second signature does not verify", compact)
+		}
+	}
+
+	mayo := crypto.NewMAYO(16, 20, 4, 5, constants.UOV_PK_SEED_LEN, constants.UOV_SK_SEED_LEN, crypto.Insecure())
+	mayo_sk, mayo_pk := mayo.KeyGen()
+	target := crypto.Nrand128(mayo.M, []byte{6})
+	for i := 0; i < 2; i++ {
+		if !mayo.Verify(target, mayo.Sign(target, mayo_sk), mayo_pk) {
+			t.Fatal("MAYO signature does not verify")
+		}
+	}
+	if err := mayo.ValidateSecretKey(mayo_sk, mayo_pk); err != nil {
+		t.Error(err)
+	}
+}